@@ -0,0 +1,160 @@
+package web
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// limitedBody fails the reads with *http.MaxBytesError once more than limit bytes are read,
+// the limit of the body on the wire may be changed by WithBodyLimit before reading, which
+// http.MaxBytesReader cannot do once Decompress has wrapped it
+type limitedBody struct {
+	io.ReadCloser
+	limit    int64
+	read     int64
+	exceeded atomic.Bool // it is read by the hook which may run while the handler goroutine of Timeout reads
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded.Load() {
+		return 0, &http.MaxBytesError{Limit: b.limit}
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// read one more byte than remaining to detect the excess, remaining+1 cannot overflow here
+	remaining := b.limit - b.read
+	if int64(len(p)) > remaining {
+		p = p[:remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= remaining {
+		b.read += int64(n)
+		return n, err
+	}
+	b.read = b.limit
+	b.exceeded.Store(true)
+	return int(remaining), &http.MaxBytesError{Limit: b.limit}
+}
+
+// guardBody closes the connection once the body exceeded its limit like http.MaxBytesReader,
+// so that the server does not read the rest of the body, the response of the handler is kept
+func guardBody(c *Context, b *limitedBody) {
+	c.BeforeWriteHeader(func() {
+		if b.exceeded.Load() {
+			c.SetHeader("Connection", "close")
+		}
+	})
+}
+
+// failIfExceeded responds 413 if the handlers did not respond after the body exceeded its limit
+func failIfExceeded(c *Context, b *limitedBody) {
+	if b.exceeded.Load() && !c.Writer.Written() {
+		c.Fail(http.StatusRequestEntityTooLarge, "request body too large")
+	}
+}
+
+// wireBody installs the limit of the body on the wire once, the later calls change the installed limit
+func wireBody(c *Context) (*limitedBody, bool) {
+	if c.bodyLimit != nil {
+		return c.bodyLimit, false
+	}
+	c.bodyLimit = &limitedBody{ReadCloser: c.Request.Body, limit: math.MaxInt64}
+	c.Request.Body = c.bodyLimit
+	guardBody(c, c.bodyLimit)
+	return c.bodyLimit, true
+}
+
+// limitBody limits the request body on the wire to n bytes, it replaces the limit installed before
+func limitBody(c *Context, n int64) bool {
+	if c.Request.ContentLength > n {
+		c.Fail(http.StatusRequestEntityTooLarge, "request body too large")
+		return false
+	}
+	b, _ := wireBody(c)
+	b.limit = n
+	return true
+}
+
+// BodyLimit is a middleware which limits the size of request body, the reads beyond the limit fail with
+// *http.MaxBytesError, and it responds 413 if the handlers did not respond to the error
+func BodyLimit(n int64) Handler {
+	return HandlerFunc(func(c *Context) {
+		if limitBody(c, n) {
+			c.Next()
+			failIfExceeded(c, c.bodyLimit)
+		}
+	})
+}
+
+// WithBodyLimit wraps the route handler to limit the size of request body, it overrides the limit of BodyLimit.
+// The limit applies to the body on the wire, the limit of Decompress is kept.
+func WithBodyLimit(n int64, handler Handler) Handler {
+	return HandlerFunc(func(c *Context) {
+		if limitBody(c, n) {
+			handler.Handle(c)
+			failIfExceeded(c, c.bodyLimit)
+		}
+	})
+}
+
+// decompressedBody creates the decompressor at the first read, so that the limit of the body on the wire
+// may still be changed by WithBodyLimit, it closes both the decompressor and the compressed body
+type decompressedBody struct {
+	encoding     string
+	compressed   io.ReadCloser
+	decompressor io.ReadCloser
+}
+
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	if b.decompressor == nil {
+		if b.encoding == "deflate" {
+			b.decompressor = flate.NewReader(b.compressed)
+		} else {
+			zr, err := gzip.NewReader(b.compressed)
+			if err != nil {
+				return 0, err
+			}
+			b.decompressor = zr
+		}
+	}
+	return b.decompressor.Read(p)
+}
+
+func (b *decompressedBody) Close() error {
+	var err error
+	if b.decompressor != nil {
+		err = b.decompressor.Close()
+	}
+	if cerr := b.compressed.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Decompress is a middleware which decodes the request body of "Content-Encoding: gzip" or "deflate" transparently,
+// the decompressed body is limited to n bytes to prevent zip bombs. An invalid body fails the reads.
+func Decompress(n int64) Handler {
+	return HandlerFunc(func(c *Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		if encoding != "gzip" && encoding != "x-gzip" && encoding != "deflate" {
+			c.Next()
+			return
+		}
+		// the decompressor reads through the limit on the wire, so that WithBodyLimit still changes it later
+		compressed, _ := wireBody(c)
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+		body := &limitedBody{ReadCloser: &decompressedBody{encoding: encoding, compressed: compressed}, limit: n}
+		c.Request.Body = body
+		guardBody(c, body)
+		c.Next()
+		failIfExceeded(c, body)
+	})
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBodyLimit(t *testing.T) {
+	s := New()
+	s.Use(BodyLimit(24), Decompress(16))
+	echo := HandlerFunc(func(c *Context) {
+		data, err := c.Binary()
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.String(http.StatusRequestEntityTooLarge, "limit %d", maxBytesErr.Limit)
+			return
+		}
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(200, "%s", data)
+	})
+	s.POST("/echo", echo)
+	s.POST("/upload", WithBodyLimit(1<<20, echo))
+
+	post := func(s *Server, path string, body []byte, gzipped bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewReader(body))
+		req.ContentLength = -1 // unknown length, so the body is checked while reading
+		if gzipped {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		w := httptest.NewRecorder()
+		(&Engine{s}).ServeHTTP(w, req)
+		return w
+	}
+	gzipped := func(data string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write([]byte(data))
+		_ = zw.Close()
+		return buf.Bytes()
+	}
+	if w := post(s, "/echo", []byte("short"), false); w.Code != 200 || w.Body.String() != "short" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if w := post(s, "/echo", []byte(strings.Repeat("a", 100)), false); w.Code != http.StatusRequestEntityTooLarge ||
+		w.Body.String() != "limit 24" || w.Header().Get("Connection") != "close" {
+		t.Fatalf("expected 413 closing the connection, got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if w := post(s, "/upload", []byte(strings.Repeat("a", 100)), false); w.Code != 200 {
+		t.Fatalf("expected the route limit to override, got %d", w.Code)
+	}
+
+	small := gzipped("hello")
+	if len(small) <= 24 {
+		t.Fatalf("the compressed body must exceed BodyLimit, got %d bytes", len(small))
+	}
+	if w := post(s, "/echo", small, true); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for the compressed body over the wire limit, got %d", w.Code)
+	}
+	if w := post(s, "/upload", small, true); w.Code != 200 || w.Body.String() != "hello" {
+		t.Fatalf("expected the route limit to raise the wire limit, got %d %q", w.Code, w.Body.String())
+	}
+	if w := post(s, "/upload", gzipped(strings.Repeat("a", 1000)), true); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for the zip bomb, got %d %q", w.Code, w.Body.String())
+	}
+
+	// Decompress alone installs an unlimited wire body
+	alone := New()
+	alone.Use(Recovery(), Decompress(math.MaxInt64))
+	alone.POST("/echo", echo)
+	if w := post(alone, "/echo", gzipped(strings.Repeat("a", 1000)), true); w.Code != 200 || w.Body.Len() != 1000 {
+		t.Fatalf("unexpected response of Decompress without BodyLimit %d %q", w.Code, w.Body.String())
+	}
+
+	timed := New()
+	timed.Use(BodyLimit(4), Timeout(time.Second))
+	timed.POST("/ignored", HandlerFunc(func(c *Context) {
+		_, _ = c.Binary()
+		c.String(200, "ok")
+	}))
+	timed.POST("/unanswered", HandlerFunc(func(c *Context) {
+		_, _ = c.Binary()
+	}))
+	if w := post(timed, "/ignored", []byte("too long body"), false); w.Code != 200 || w.Body.String() != "ok" {
+		t.Fatalf("expected the response of the handler to be kept, got %d %q", w.Code, w.Body.String())
+	}
+	if w := post(timed, "/unanswered", []byte("too long body"), false); w.Code != http.StatusRequestEntityTooLarge ||
+		w.Body.String() != "request body too large" {
+		t.Fatalf("expected a single 413 response under Timeout, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,241 @@
+package web
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressConfig defines the config of CompressWithConfig
+type CompressConfig struct {
+	// Level is the compression level of gzip and deflate, default is flate.DefaultCompression
+	Level int
+	// MinLength is the minimum body size to compress, default is 1024 bytes
+	MinLength int
+	// ExcludedContentTypes are the content types which are not compressed besides the built-in compressed ones
+	ExcludedContentTypes []string
+}
+
+// the content types which are compressed already, a type ending with "/" matches by prefix
+var compressedContentTypes = []string{
+	"image/", "video/", "audio/", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd", "application/x-brotli",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/x-bzip2", "application/x-xz",
+}
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+const (
+	compressUndecided = iota
+	compressing
+	compressPassthrough
+)
+
+// compressWriter buffers the beginning of the body to decide whether to compress it
+type compressWriter struct {
+	ResponseWriter
+	encoding   string
+	pool       *sync.Pool
+	compressor compressor
+	config     *CompressConfig
+	buf        []byte
+	state      int
+	started    bool
+	head       bool
+}
+
+// negotiateEncoding picks gzip or deflate from Accept-Encoding by the q-values, "" means no compression
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	qs := map[string]float64{}
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		q := 1.0
+		if name, value, found := strings.Cut(strings.TrimSpace(params), "="); found && strings.TrimSpace(name) == "q" {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = v
+			}
+		}
+		qs[coding] = q
+	}
+	for _, coding := range []string{"gzip", "deflate"} {
+		q, has := qs[coding]
+		if !has {
+			q, has = qs["*"]
+		}
+		if has && q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	if w.head || h.Get("Content-Encoding") != "" {
+		return false
+	}
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		// set it now, otherwise net/http would sniff the compressed bytes
+		contentType = http.DetectContentType(w.buf)
+		h.Set("Content-Type", contentType)
+	}
+	contentType = strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	for _, list := range [][]string{compressedContentTypes, w.config.ExcludedContentTypes} {
+		for _, t := range list {
+			if contentType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(contentType, t)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// decide chooses to compress or not and writes the buffered data
+func (w *compressWriter) decide(checkLength bool) error {
+	if w.compressible() && (!checkLength || len(w.buf) >= w.config.MinLength) {
+		w.state = compressing
+		h := w.Header()
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+		w.compressor = w.pool.Get().(compressor)
+		w.compressor.Reset(w.ResponseWriter)
+	} else {
+		w.state = compressPassthrough
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.state == compressing {
+		_, err := w.compressor.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.started = true
+	switch w.state {
+	case compressing:
+		return w.compressor.Write(data)
+	case compressPassthrough:
+		return w.ResponseWriter.Write(data)
+	}
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.config.MinLength {
+		if err := w.decide(false); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// WriteHeaderNow is delayed until the compression is decided
+func (w *compressWriter) WriteHeaderNow() {
+	w.started = true
+}
+
+func (w *compressWriter) Written() bool {
+	return w.started || w.ResponseWriter.Written()
+}
+
+// Flush decides to compress the streaming response regardless of MinLength
+func (w *compressWriter) Flush() {
+	if w.state == compressUndecided {
+		if err := w.decide(false); err != nil {
+			return
+		}
+	}
+	if w.state == compressing {
+		if err := w.compressor.Flush(); err != nil {
+			return
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close writes the rest of the response and returns the compressor to the pool
+func (w *compressWriter) close() {
+	if w.state == compressUndecided && w.started {
+		_ = w.decide(true)
+	}
+	if w.state == compressing {
+		_ = w.compressor.Close()
+		w.compressor.Reset(io.Discard)
+		w.pool.Put(w.compressor)
+		w.compressor = nil
+	}
+	if w.started {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Compress is a middleware which compresses the responses by gzip or deflate with the default config
+func Compress() Handler {
+	return CompressWithConfig(CompressConfig{})
+}
+
+// CompressWithConfig is a middleware which compresses the responses by gzip or deflate negotiated with Accept-Encoding
+func CompressWithConfig(config CompressConfig) Handler {
+	if config.Level == 0 {
+		config.Level = flate.DefaultCompression
+	}
+	if config.MinLength <= 0 {
+		config.MinLength = 1024
+	}
+	if _, err := flate.NewWriter(io.Discard, config.Level); err != nil {
+		panic(err)
+	}
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, config.Level)
+			return w
+		}},
+		"deflate": {New: func() any {
+			w, _ := flate.NewWriter(io.Discard, config.Level)
+			return w
+		}},
+	}
+	return HandlerFunc(func(c *Context) {
+		c.AddHeader("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			pool:           pools[encoding],
+			config:         &config,
+			head:           c.Method == http.MethodHead,
+		}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	})
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := map[string]string{
+		"gzip, deflate":              "gzip",
+		"gzip;q=0.5, deflate":        "deflate",
+		"gzip;q=0, deflate;q=0":      "",
+		"*":                          "gzip",
+		"br, identity":               "",
+		"deflate;q=0.8, *;q=0.9, br": "gzip",
+	}
+	for header, expect := range cases {
+		if got := negotiateEncoding(header); got != expect {
+			t.Errorf("%q: expected %q, got %q", header, expect, got)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	s := New()
+	s.Use(Compress())
+	large := strings.Repeat("needle ", 1000)
+	s.GET("/large", HandlerFunc(func(c *Context) { c.JSON(200, H{"data": large}) }))
+	s.GET("/small", HandlerFunc(func(c *Context) { c.String(200, "small") }))
+	s.GET("/image", HandlerFunc(func(c *Context) { c.Data(200, "image/png", []byte(large)) }))
+	s.GET("/stream", HandlerFunc(func(c *Context) {
+		_, _ = c.Writer.Write([]byte("chunk"))
+		c.Writer.Flush()
+	}))
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		(&Engine{s}).ServeHTTP(w, req)
+		return w
+	}
+	w := request("/large")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	r, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(r)
+	if !strings.Contains(string(body), large) {
+		t.Fatalf("unexpected body %q", body)
+	}
+	for _, path := range []string{"/small", "/image"} {
+		if w = request(path); w.Header().Get("Content-Encoding") != "" {
+			t.Fatalf("%s: expected no compression, got %v", path, w.Header())
+		}
+	}
+	if w = request("/stream"); w.Header().Get("Content-Encoding") != "gzip" || !w.Flushed {
+		t.Fatalf("expected the flushed stream to be compressed, got %v", w.Header())
+	}
+}
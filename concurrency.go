@@ -0,0 +1,138 @@
+package web
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ConcurrencyConfig defines the config of MaxInFlightWithConfig
+type ConcurrencyConfig struct {
+	// Limit is the max count of the in-flight requests, it is the initial limit in the adaptive mode
+	Limit int
+	// QueueSize is the max count of the requests waiting for a slot, 0 means rejecting immediately
+	QueueSize int
+	// QueueTimeout is how long a request waits in the queue, default is 1 second
+	QueueTimeout time.Duration
+	// RetryAfter is the value of "Retry-After" in the 503 response, default is 1 second
+	RetryAfter time.Duration
+	// Adaptive enables AIMD: the limit increases additively while the latency is below LatencyThreshold,
+	// and decreases multiplicatively when it rises above, to shed load on slow endpoints
+	Adaptive         bool
+	LatencyThreshold time.Duration // it must be positive in the adaptive mode
+	MinLimit         int           // default is 1
+	MaxLimit         int           // default is 10 times Limit
+}
+
+type concurrencyLimiter struct {
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	waiters  []chan struct{} // FIFO queue, a closed channel means the slot is granted
+	config   *ConcurrencyConfig
+}
+
+// acquire takes a slot, it waits in the queue if there is room
+func (l *concurrencyLimiter) acquire(done <-chan struct{}) bool {
+	l.mu.Lock()
+	if l.inFlight < int(l.limit) {
+		l.inFlight++
+		l.mu.Unlock()
+		return true
+	}
+	if len(l.waiters) >= l.config.QueueSize {
+		l.mu.Unlock()
+		return false
+	}
+	ch := make(chan struct{})
+	l.waiters = append(l.waiters, ch)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.config.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return true
+	case <-timer.C:
+	case <-done:
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, waiter := range l.waiters {
+		if waiter == ch {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return false
+		}
+	}
+	// the slot was granted while timing out, give it back
+	l.inFlight--
+	l.grant()
+	return false
+}
+
+// release gives back the slot and adjusts the limit by the latency in the adaptive mode
+func (l *concurrencyLimiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.config.Adaptive {
+		if latency > l.config.LatencyThreshold {
+			l.limit = math.Max(float64(l.config.MinLimit), l.limit*0.9)
+		} else {
+			l.limit = math.Min(float64(l.config.MaxLimit), l.limit+1/l.limit)
+		}
+	}
+	l.inFlight--
+	l.grant()
+}
+
+// grant wakes up the waiters while there are free slots
+func (l *concurrencyLimiter) grant() {
+	for len(l.waiters) > 0 && l.inFlight < int(l.limit) {
+		l.inFlight++
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+	}
+}
+
+// MaxInFlight is a middleware which limits the count of the in-flight requests, it responds 503 if the limit is reached
+func MaxInFlight(n int) Handler {
+	return MaxInFlightWithConfig(ConcurrencyConfig{Limit: n})
+}
+
+// MaxInFlightWithConfig is a middleware which limits the in-flight requests with a wait queue and the adaptive limit,
+// use it on a group to limit the group only
+func MaxInFlightWithConfig(config ConcurrencyConfig) Handler {
+	if config.Limit <= 0 {
+		panic("the limit of in-flight requests must > 0")
+	}
+	if config.QueueTimeout <= 0 {
+		config.QueueTimeout = time.Second
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+	if config.Adaptive {
+		if config.LatencyThreshold <= 0 {
+			panic("the latency threshold of adaptive concurrency limit must > 0")
+		}
+		if config.MinLimit <= 0 {
+			config.MinLimit = 1
+		}
+		if config.MaxLimit <= 0 {
+			config.MaxLimit = config.Limit * 10
+		}
+	}
+	l := &concurrencyLimiter{limit: float64(config.Limit), config: &config}
+	retryAfter := ceilSeconds(config.RetryAfter)
+	return HandlerFunc(func(c *Context) {
+		if !l.acquire(c.Request.Context().Done()) {
+			c.SetHeader("Retry-After", retryAfter)
+			c.Fail(http.StatusServiceUnavailable, "server overloaded")
+			return
+		}
+		start := time.Now()
+		defer func() { l.release(time.Since(start)) }()
+		c.Next()
+	})
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxInFlight(t *testing.T) {
+	s := New()
+	release := make(chan struct{})
+	s.Use(MaxInFlightWithConfig(ConcurrencyConfig{Limit: 1, QueueSize: 1, QueueTimeout: time.Second}))
+	s.GET("/slow", HandlerFunc(func(c *Context) {
+		<-release
+		c.String(200, "ok")
+	}))
+
+	codes := make(chan int, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- serve(s, "GET", "/slow").Code
+		}()
+		time.Sleep(10 * time.Millisecond)
+	}
+	// one is in flight, one is queued and one is rejected
+	if code := <-codes; code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", code)
+	}
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != 200 {
+			t.Fatalf("expected 200, got %d", code)
+		}
+	}
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	config := &ConcurrencyConfig{Adaptive: true, LatencyThreshold: time.Second, MinLimit: 2, MaxLimit: 8}
+	l := &concurrencyLimiter{limit: 4, config: config}
+	for i := 0; i < 20; i++ {
+		l.acquire(nil)
+		l.release(2 * time.Second)
+	}
+	if l.limit != 2 {
+		t.Fatalf("expected the limit to drop to the min, got %v", l.limit)
+	}
+	for i := 0; i < 100; i++ {
+		l.acquire(nil)
+		l.release(time.Millisecond)
+	}
+	if l.limit != 8 {
+		t.Fatalf("expected the limit to grow to the max, got %v", l.limit)
+	}
+}
@@ -1,9 +1,11 @@
 package web
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net"
 	"net/http"
@@ -14,14 +16,22 @@ type H map[string]any
 
 type Context struct {
 	// origin objects
-	Writer  http.ResponseWriter
+	Writer  ResponseWriter
 	Request *http.Request
-	// request info
-	Path   string
-	Method string
-	params map[string]string
-	// response info
+	writer  *responseWriter // the base of Writer
+	// StatusCode is the status code set by Status.
+	//
+	// Deprecated: use c.Writer.Status(), which also reflects the status written by other means.
 	StatusCode int
+	// request info
+	Path      string
+	Method    string
+	params    map[string]string
+	fullPath  string // the pattern of the matched route
+	routeName string // the name of the matched route
+	requestID string
+	span      *Span        // the server span of Tracing
+	bodyLimit *limitedBody // the limit of the request body on the wire
 	// extra info
 	extras map[string]any
 	// middlewares and route
@@ -29,16 +39,18 @@ type Context struct {
 	index    int
 	// server pointer
 	server *Server
-	// response tag
-	isResponse bool
+	// whether the handlers run under a timeout already
+	inTimeout bool
 }
 
 func newContext(w http.ResponseWriter, req *http.Request) *Context {
+	writer := newResponseWriter(w)
 	return &Context{
-		Path:    req.URL.Path,
+		Path:    cleanPath(req.URL.Path),
 		Method:  req.Method,
 		Request: req,
-		Writer:  w,
+		Writer:  writer,
+		writer:  writer,
 		extras:  make(map[string]any),
 		index:   -1,
 	}
@@ -76,6 +88,16 @@ func (c *Context) Param(key string) string {
 	return value
 }
 
+// FullPath returns the registered pattern of the matched route such as "/users/:id", or "" if no route matched
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// RouteName returns the name of the matched route given by Named
+func (c *Context) RouteName() string {
+	return c.routeName
+}
+
 // Extra is used to get the info which set by user
 func (c *Context) Extra(key string) any {
 	value, _ := c.extras[key]
@@ -87,17 +109,44 @@ func (c *Context) SetExtra(key string, v any) {
 	c.extras[key] = v
 }
 
+// Logger returns the logger of the server with the attributes of the request
+func (c *Context) Logger() *slog.Logger {
+	logger := defaultLogger
+	if c.server != nil {
+		logger = c.server.logger
+	}
+	attrs := make([]any, 0, 10)
+	if c.requestID != "" {
+		attrs = append(attrs, "request_id", c.requestID)
+	}
+	if c.span != nil {
+		attrs = append(attrs, "trace_id", hex.EncodeToString(c.span.Context.TraceID[:]))
+	}
+	attrs = append(attrs, "method", c.Method, "path", c.Path)
+	if c.fullPath != "" {
+		attrs = append(attrs, "route", c.fullPath)
+	}
+	return logger.With(attrs...)
+}
+
 func (c *Context) GetHeader(key string) string {
 	return c.Request.Header.Get(key)
 }
 
-func (c *Context)  ClientIp() string{
+func (c *Context) ClientIp() string {
 	remoteAddr := c.Request.RemoteAddr
 	forwardedFor := c.GetHeader("X-Forwarded-For")
 	if forwardedFor != "" {
-		remoteAddr = strings.Split(forwardedFor, ",")[0]
+		remoteAddr = strings.TrimSpace(strings.Split(forwardedFor, ",")[0])
+	}
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		remoteAddr = host
 	}
-	return net.ParseIP(remoteAddr).String()
+	ip := net.ParseIP(remoteAddr)
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
 }
 
 func (c *Context) FormData(key string) string {
@@ -121,13 +170,13 @@ func (c *Context) Binary() ([]byte, error) {
 	var tmp = make([]byte, 128)
 	for {
 		n, err := c.Request.Body.Read(tmp)
+		content = append(content, tmp[:n]...)
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			return nil, err
 		}
-		content = append(content, tmp[:n]...)
 	}
 	return content, nil
 }
@@ -136,17 +185,41 @@ func (c *Context) FormFile(key string) (multipart.File, *multipart.FileHeader, e
 	return c.Request.FormFile(key)
 }
 
+// BeforeWriteHeader registers fn to be called right before the response headers are sent,
+// it is the last chance for middlewares to change the headers such as setting cookies
+func (c *Context) BeforeWriteHeader(fn func()) {
+	c.writer.beforeWrite = append(c.writer.beforeWrite, fn)
+}
+
+// Status sets the status code of the response, it is sent with the headers at the first write
 func (c *Context) Status(code int) {
-	c.StatusCode = code
 	c.Writer.WriteHeader(code)
+	c.StatusCode = c.Writer.Status()
 }
 
+// SetHeader sets the response header, it replaces any existing values of the key
 func (c *Context) SetHeader(key string, value string) {
 	c.Writer.Header().Set(key, value)
 }
 
+// AddHeader adds the value to the response header, it appends to any existing values of the key
+func (c *Context) AddHeader(key string, value string) {
+	c.Writer.Header().Add(key, value)
+}
+
+// Redirect replies to the request with a redirect to location, code must be a 3xx status code or 201
+func (c *Context) Redirect(code int, location string) {
+	if (code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect) && code != http.StatusCreated {
+		panic(fmt.Sprintf("cannot redirect with status code %d", code))
+	}
+	if c.Writer.Written() {
+		return
+	}
+	http.Redirect(c.Writer, c.Request, location, code)
+}
+
 func (c *Context) String(code int, format string, values ...any) {
-	if c.isResponse {
+	if c.Writer.Written() {
 		return
 	}
 	c.SetHeader("Content-Type", "text/plain")
@@ -154,11 +227,10 @@ func (c *Context) String(code int, format string, values ...any) {
 	if _, err := c.Writer.Write([]byte(fmt.Sprintf(format, values...))); err != nil {
 		panic(err)
 	}
-	c.isResponse = true
 }
 
 func (c *Context) JSON(code int, obj any) {
-	if c.isResponse {
+	if c.Writer.Written() {
 		return
 	}
 	c.SetHeader("Content-Type", "application/json")
@@ -167,11 +239,10 @@ func (c *Context) JSON(code int, obj any) {
 	if err := encoder.Encode(obj); err != nil {
 		panic(err)
 	}
-	c.isResponse = true
 }
 
 func (c *Context) Data(code int, contentType string, data []byte) {
-	if c.isResponse {
+	if c.Writer.Written() {
 		return
 	}
 	c.SetHeader("Content-Type", contentType)
@@ -179,11 +250,10 @@ func (c *Context) Data(code int, contentType string, data []byte) {
 	if _, err := c.Writer.Write(data); err != nil {
 		panic(err)
 	}
-	c.isResponse = true
 }
 
 func (c *Context) HTML(code int, name string, data interface{}) {
-	if c.isResponse {
+	if c.Writer.Written() {
 		return
 	}
 	c.SetHeader("Content-Type", "text/html")
@@ -191,7 +261,6 @@ func (c *Context) HTML(code int, name string, data interface{}) {
 	if err := c.server.htmlTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
 		panic(err)
 	}
-	c.isResponse = true
 }
 
 func (c *Context) Fail(code int, err string) {
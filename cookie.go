@@ -0,0 +1,68 @@
+package web
+
+import (
+	"net/http"
+	"time"
+)
+
+// Cookie defines the cookie which is set by Context.SetCookie
+type Cookie struct {
+	Name        string
+	Value       string
+	Path        string // default is the cookie path of Server, or "/" if it is not set
+	Domain      string // default is the cookie domain of Server
+	MaxAge      int    // MaxAge<0 means deleting the cookie, MaxAge=0 means no 'Max-Age' attribute
+	Expires     time.Time
+	Secure      bool
+	HttpOnly    bool
+	SameSite    http.SameSite
+	Partitioned bool // CHIPS, it requires Secure which is set automatically
+}
+
+// SetCookieDefaults sets the default domain and path of the cookies set by Context.SetCookie
+func (server *Server) SetCookieDefaults(domain, path string) {
+	server.cookieDomain = domain
+	server.cookiePath = path
+}
+
+// SetCookie adds a Set-Cookie header to the response
+func (c *Context) SetCookie(cookie *Cookie) {
+	path, domain := cookie.Path, cookie.Domain
+	if path == "" && c.server != nil {
+		path = c.server.cookiePath
+	}
+	if path == "" {
+		path = "/"
+	}
+	if domain == "" && c.server != nil {
+		domain = c.server.cookieDomain
+	}
+	hc := &http.Cookie{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Path:     path,
+		Domain:   domain,
+		MaxAge:   cookie.MaxAge,
+		Expires:  cookie.Expires,
+		Secure:   cookie.Secure || cookie.Partitioned,
+		HttpOnly: cookie.HttpOnly,
+		SameSite: cookie.SameSite,
+	}
+	value := hc.String()
+	if value == "" {
+		return
+	}
+	if cookie.Partitioned {
+		value += "; Partitioned"
+	}
+	c.AddHeader("Set-Cookie", value)
+}
+
+// Cookie returns the value of the named cookie in the request, or http.ErrNoCookie if not found
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return cookie.Value, nil
+}
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCookieAndRedirect(t *testing.T) {
+	s := New()
+	s.SetCookieDefaults("example.com", "/app")
+	s.GET("/set", HandlerFunc(func(c *Context) {
+		c.SetCookie(&Cookie{Name: "a", Value: "1", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		c.SetCookie(&Cookie{Name: "b", Value: "2", Path: "/", SameSite: http.SameSiteNoneMode, Partitioned: true})
+		c.AddHeader("X-Multi", "1")
+		c.AddHeader("X-Multi", "2")
+		c.Redirect(http.StatusFound, "/get")
+	}))
+	s.GET("/bad", HandlerFunc(func(c *Context) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected redirect with status 200 to panic")
+			}
+			c.String(200, "ok")
+		}()
+		c.Redirect(http.StatusOK, "/get")
+	}))
+
+	w := serve(s, "GET", "/set")
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/get" {
+		t.Fatalf("unexpected redirect %d %v", w.Code, w.Header())
+	}
+	cookies := w.Header().Values("Set-Cookie")
+	if len(cookies) != 2 ||
+		cookies[0] != "a=1; Path=/app; Domain=example.com; HttpOnly; SameSite=Strict" ||
+		!strings.HasSuffix(cookies[1], "; Secure; SameSite=None; Partitioned") {
+		t.Fatalf("unexpected cookies %q", cookies)
+	}
+	if len(w.Header().Values("X-Multi")) != 2 {
+		t.Fatalf("expected 2 header values, got %v", w.Header().Values("X-Multi"))
+	}
+	if w := serve(s, "GET", "/bad"); w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
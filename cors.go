@@ -0,0 +1,116 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig defines the config of CORS
+type CORSConfig struct {
+	// AllowOrigins are the allowed origins, "*" allows all, "https://*.example.com" allows the subdomains
+	AllowOrigins []string
+	// AllowOriginFunc reports whether the origin is allowed, it is checked after AllowOrigins
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods default is GET, HEAD, POST, PUT, PATCH and DELETE
+	AllowMethods []string
+	// AllowHeaders default is the headers requested by the preflight request
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	// MaxAge is how long the result of a preflight request can be cached, 0 means no 'Access-Control-Max-Age'
+	MaxAge time.Duration
+}
+
+// CORS is a middleware which implements cross-origin resource sharing,
+// it answers the preflight requests itself so that no OPTIONS route is required
+func CORS(config CORSConfig) Handler {
+	if len(config.AllowMethods) == 0 {
+		config.AllowMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	}
+	allowAll := false
+	exact := make(map[string]struct{})
+	var wildcards [][2]string // scheme + "://" and "." + domain
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		if origin == "*" {
+			allowAll = true
+		} else if scheme, domain, found := strings.Cut(origin, "://*."); found {
+			wildcards = append(wildcards, [2]string{scheme + "://", "." + domain})
+		} else {
+			exact[origin] = struct{}{}
+		}
+	}
+	allowed := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		lower := strings.ToLower(origin)
+		if _, has := exact[lower]; has {
+			return true
+		}
+		for _, w := range wildcards {
+			if strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) && len(lower) > len(w[0])+len(w[1]) {
+				return true
+			}
+		}
+		return config.AllowOriginFunc != nil && config.AllowOriginFunc(origin)
+	}
+	// the response depends on the request origin unless all origins get "*"
+	varyOrigin := !allowAll || config.AllowCredentials
+	allowMethods := strings.Join(config.AllowMethods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge / time.Second))
+
+	return HandlerFunc(func(c *Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if varyOrigin {
+			c.AddHeader("Vary", "Origin")
+		}
+		if preflight {
+			c.AddHeader("Vary", "Access-Control-Request-Method")
+			c.AddHeader("Vary", "Access-Control-Request-Headers")
+		}
+		if origin == "" {
+			c.Next()
+			return
+		}
+		if !allowed(origin) {
+			if preflight {
+				c.Fail(http.StatusForbidden, "origin not allowed")
+				return
+			}
+			c.Next()
+			return
+		}
+		if varyOrigin {
+			c.SetHeader("Access-Control-Allow-Origin", origin)
+		} else {
+			c.SetHeader("Access-Control-Allow-Origin", "*")
+		}
+		if config.AllowCredentials {
+			c.SetHeader("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if exposeHeaders != "" {
+				c.SetHeader("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+		c.SetHeader("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			c.SetHeader("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+			c.SetHeader("Access-Control-Allow-Headers", requested)
+		}
+		if config.MaxAge > 0 {
+			c.SetHeader("Access-Control-Max-Age", maxAge)
+		}
+		c.Abort()
+		c.Status(http.StatusNoContent)
+	})
+}
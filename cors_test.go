@@ -0,0 +1,46 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	s := New()
+	s.Use(CORS(CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.example.org"},
+		AllowCredentials: true,
+		ExposeHeaders:    []string{"X-Total"},
+		MaxAge:           time.Hour,
+	}))
+	s.GET("/items", HandlerFunc(func(c *Context) { c.String(200, "ok") }))
+
+	request := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/items", nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", "GET")
+			req.Header.Set("Access-Control-Request-Headers", "X-Token")
+		}
+		w := httptest.NewRecorder()
+		(&Engine{s}).ServeHTTP(w, req)
+		return w
+	}
+	w := request(http.MethodOptions, "https://a.example.org")
+	if w.Code != http.StatusNoContent ||
+		w.Header().Get("Access-Control-Allow-Origin") != "https://a.example.org" ||
+		w.Header().Get("Access-Control-Allow-Headers") != "X-Token" ||
+		w.Header().Get("Access-Control-Max-Age") != "3600" {
+		t.Fatalf("unexpected preflight response %d %v", w.Code, w.Header())
+	}
+	w = request(http.MethodGet, "https://app.example.com")
+	if w.Code != 200 || w.Header().Get("Access-Control-Allow-Credentials") != "true" ||
+		w.Header().Get("Access-Control-Expose-Headers") != "X-Total" || w.Header().Get("Vary") != "Origin" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	if w = request(http.MethodOptions, "https://evil.com"); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", w.Code)
+	}
+}
@@ -0,0 +1,184 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CSRFMode defines how the CSRF token is kept
+type CSRFMode int
+
+const (
+	// CSRFDoubleSubmit keeps the token in a cookie and compares it with the submitted one
+	CSRFDoubleSubmit CSRFMode = iota
+	// CSRFSynchronizer keeps the token in the session, it requires the Sessions middleware
+	CSRFSynchronizer
+)
+
+var (
+	ErrCSRFOrigin = errors.New("CSRF origin check failed")
+	ErrCSRFToken  = errors.New("CSRF token mismatch")
+)
+
+// CSRFConfig defines the config of CSRF
+type CSRFConfig struct {
+	Mode           CSRFMode
+	CookieName     string   // the cookie of the token in CSRFDoubleSubmit mode, default is "_csrf"
+	HeaderName     string   // the header of the submitted token, default is "X-CSRF-Token"
+	FieldName      string   // the form field of the submitted token, default is "_csrf"
+	TrustedOrigins []string // the origins allowed besides the host of the request, such as "https://example.com"
+	// SkipPaths are the paths which are not checked, a path ending with "*" matches by prefix
+	SkipPaths []string
+	// Cookie defines the attributes of the token cookie, Name and Value are ignored
+	Cookie Cookie
+	// ErrorHandler responds to the rejected requests, default is a 403 with the error message
+	ErrorHandler func(c *Context, err error)
+}
+
+type csrfToken struct {
+	field string
+	value string
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// CSRFToken returns the CSRF token of the request, it requires the CSRF middleware
+func (c *Context) CSRFToken() string {
+	token, _ := c.Extra("csrf").(*csrfToken)
+	if token == nil {
+		return ""
+	}
+	return token.value
+}
+
+// CSRFField returns the hidden input which carries the CSRF token in a form
+func (c *Context) CSRFField() template.HTML {
+	token, _ := c.Extra("csrf").(*csrfToken)
+	if token == nil {
+		return ""
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(token.field) +
+		`" value="` + template.HTMLEscapeString(token.value) + `">`)
+}
+
+// csrfField is the template function which renders Context.CSRFField, such as {{ csrfField .ctx }}
+func csrfField(c *Context) template.HTML {
+	if c == nil {
+		return ""
+	}
+	return c.CSRFField()
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions || method == http.MethodTrace
+}
+
+// checkOrigin checks the Origin or Referer of the request against its host and the trusted origins
+func checkOrigin(c *Context, trusted map[string]struct{}) bool {
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		referer := c.GetHeader("Referer")
+		if referer == "" {
+			// browsers always send Referer for the same-origin requests over https unless disabled
+			return c.Request.TLS == nil
+		}
+		origin = referer
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Host == c.Request.Host {
+		return true
+	}
+	_, has := trusted[u.Scheme+"://"+u.Host]
+	return has
+}
+
+// CSRF is a middleware which protects the unsafe methods against cross-site request forgery
+func CSRF(config CSRFConfig) Handler {
+	if config.CookieName == "" {
+		config.CookieName = "_csrf"
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	if config.FieldName == "" {
+		config.FieldName = "_csrf"
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = func(c *Context, err error) {
+			c.Fail(http.StatusForbidden, err.Error())
+		}
+	}
+	trusted := make(map[string]struct{}, len(config.TrustedOrigins))
+	for _, origin := range config.TrustedOrigins {
+		trusted[strings.TrimSuffix(origin, "/")] = struct{}{}
+	}
+	skip := func(path string) bool {
+		for _, p := range config.SkipPaths {
+			if p == path || (strings.HasSuffix(p, "*") && strings.HasPrefix(path, p[:len(p)-1])) {
+				return true
+			}
+		}
+		return false
+	}
+	return HandlerFunc(func(c *Context) {
+		if skip(c.Path) {
+			c.Next()
+			return
+		}
+		// load or create the token
+		var token string
+		if config.Mode == CSRFSynchronizer {
+			session := c.Session()
+			if session == nil {
+				panic("the CSRF synchronizer mode requires the Sessions middleware")
+			}
+			token, _ = session.Get("_csrf").(string)
+			if token == "" {
+				token = newCSRFToken()
+				session.Set("_csrf", token)
+			}
+		} else {
+			token, _ = c.Cookie(config.CookieName)
+			if token == "" {
+				token = newCSRFToken()
+				cookie := config.Cookie
+				cookie.Name, cookie.Value = config.CookieName, token
+				c.SetCookie(&cookie)
+			}
+		}
+		c.SetExtra("csrf", &csrfToken{config.FieldName, token})
+
+		if !isSafeMethod(c.Method) {
+			if !checkOrigin(c, trusted) {
+				config.ErrorHandler(c, ErrCSRFOrigin)
+				c.Abort()
+				return
+			}
+			submitted := c.GetHeader(config.HeaderName)
+			if submitted == "" {
+				submitted = c.FormData(config.FieldName)
+			}
+			if submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+				config.ErrorHandler(c, ErrCSRFToken)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	})
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	s := New()
+	s.Use(CSRF(CSRFConfig{SkipPaths: []string{"/hooks/*"}}))
+	s.GET("/form", HandlerFunc(func(c *Context) { c.String(200, "%s", c.CSRFField()) }))
+	s.POST("/form", HandlerFunc(func(c *Context) { c.String(200, "ok") }))
+	s.POST("/hooks/github", HandlerFunc(func(c *Context) { c.String(200, "ok") }))
+
+	w := serve(s, "GET", "/form")
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !strings.Contains(w.Body.String(), `name="_csrf" value="`+cookies[0].Value+`"`) {
+		t.Fatalf("unexpected form %q with cookies %v", w.Body.String(), cookies)
+	}
+	post := func(token, origin string) int {
+		req := httptest.NewRequest("POST", "/form", strings.NewReader(url.Values{"_csrf": {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Origin", origin)
+		req.AddCookie(cookies[0])
+		w := httptest.NewRecorder()
+		(&Engine{s}).ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := post(cookies[0].Value, "http://example.com"); code != 200 {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := post("forged", "http://example.com"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a wrong token, got %d", code)
+	}
+	if code := post(cookies[0].Value, "http://evil.com"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a cross origin, got %d", code)
+	}
+	if w := serve(s, "POST", "/hooks/github"); w.Code != 200 {
+		t.Fatalf("expected the skipped path to pass, got %d", w.Code)
+	}
+}
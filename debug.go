@@ -0,0 +1,169 @@
+package web
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const (
+	// ReleaseMode is the default mode of Server
+	ReleaseMode = "release"
+	// DebugMode renders the panics as the debug pages with the source code, never use it in production
+	DebugMode = "debug"
+)
+
+// ModeEnv is the environment variable which forces the mode, "release" prevents the debug mode from being set
+const ModeEnv = "NEEDLE_WEB_MODE"
+
+// SetMode sets the mode of the server, the debug mode is ignored if ModeEnv is "release"
+func (server *Server) SetMode(mode string) {
+	if mode == DebugMode && os.Getenv(ModeEnv) == ReleaseMode {
+		server.logger.Warn("The debug mode is ignored in release mode", "env", ModeEnv)
+		return
+	}
+	if mode != DebugMode && mode != ReleaseMode {
+		panic("unknown mode " + mode)
+	}
+	server.mode = mode
+}
+
+// IsDebug reports whether the server is in debug mode
+func (server *Server) IsDebug() bool {
+	return server.mode == DebugMode && os.Getenv(ModeEnv) != ReleaseMode
+}
+
+type debugLine struct {
+	Number  int
+	Code    string
+	Current bool
+}
+
+type debugFrame struct {
+	Function string
+	File     string
+	Line     int
+	Source   []debugLine
+}
+
+type debugPair struct {
+	Key   string
+	Value string
+}
+
+type debugPage struct {
+	Panic   string
+	Method  string
+	URI     string
+	Frames  []debugFrame
+	Headers []debugPair
+	Params  []debugPair
+	Query   []debugPair
+	Extras  []debugPair
+}
+
+// sourceLines reads the lines around line of file, it returns nil if the file is not readable
+func sourceLines(file string, line, around int) []debugLine {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var lines []debugLine
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan() && n <= line+around; n++ {
+		if n >= line-around {
+			lines = append(lines, debugLine{n, scanner.Text(), n == line})
+		}
+	}
+	return lines
+}
+
+// debugFrames returns the frames of the panicking goroutine without the runtime ones
+func debugFrames(pcs []uintptr) []debugFrame {
+	frames := runtime.CallersFrames(pcs)
+	var result []debugFrame
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			result = append(result, debugFrame{frame.Function, frame.File, frame.Line, sourceLines(frame.File, frame.Line, 5)})
+		}
+		if !more {
+			break
+		}
+	}
+	return result
+}
+
+func sortedPairs[V any](m map[string]V, format func(V) string) []debugPair {
+	pairs := make([]debugPair, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, debugPair{k, format(v)})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>panic: {{.Panic}}</title>
+<style>
+body{font-family:sans-serif;margin:0;background:#f5f5f5;color:#222}
+header{background:#b71c1c;color:#fff;padding:16px 24px}
+h1{font-size:20px;margin:0 0 8px;word-break:break-all}
+section{background:#fff;margin:16px 24px;padding:12px 16px;border-radius:4px}
+h2{font-size:16px;margin:4px 0 12px}
+.frame{margin-bottom:12px}
+.func{font-weight:bold}
+.file{color:#666;font-size:13px}
+pre{background:#263238;color:#eceff1;padding:8px;margin:4px 0;overflow:auto;font-size:13px}
+.current{background:#b71c1c;display:block}
+table{border-collapse:collapse;width:100%;font-size:13px}
+td{border-bottom:1px solid #eee;padding:4px 8px;vertical-align:top;word-break:break-all}
+td:first-child{width:25%;font-weight:bold}
+</style>
+</head>
+<body>
+<header><h1>panic: {{.Panic}}</h1>{{.Method}} {{.URI}}</header>
+<section><h2>Stack</h2>
+{{range .Frames}}<div class="frame"><div class="func">{{.Function}}</div><div class="file">{{.File}}:{{.Line}}</div>
+{{if .Source}}<pre>{{range .Source}}<span{{if .Current}} class="current"{{end}}>{{printf "%5d" .Number}}  {{.Code}}</span>
+{{end}}</pre>{{end}}</div>
+{{end}}</section>
+{{define "pairs"}}<table>{{range .}}<tr><td>{{.Key}}</td><td>{{.Value}}</td></tr>{{else}}<tr><td>(empty)</td><td></td></tr>{{end}}</table>{{end}}
+<section><h2>Params</h2>{{template "pairs" .Params}}</section>
+<section><h2>Query</h2>{{template "pairs" .Query}}</section>
+<section><h2>Headers</h2>{{template "pairs" .Headers}}</section>
+<section><h2>Extras</h2>{{template "pairs" .Extras}}</section>
+</body>
+</html>
+`))
+
+// renderDebugPage renders the panic with the frames of pcs and the request
+func renderDebugPage(c *Context, err any, pcs []uintptr) {
+	page := &debugPage{
+		Panic:   fmt.Sprintf("%v", err),
+		Method:  c.Method,
+		URI:     c.Request.RequestURI,
+		Frames:  debugFrames(pcs),
+		Headers: sortedPairs(c.Request.Header, func(v []string) string { return strings.Join(v, ", ") }),
+		Params:  sortedPairs(c.params, func(v string) string { return v }),
+		Query:   sortedPairs(c.Request.URL.Query(), func(v []string) string { return strings.Join(v, ", ") }),
+		Extras:  sortedPairs(c.extras, func(v any) string { return fmt.Sprintf("%#v", v) }),
+	}
+	if c.Writer.Written() {
+		return
+	}
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusInternalServerError)
+	if err := debugTemplate.Execute(c.Writer, page); err != nil {
+		c.Logger().Error("debug page rendering error", "error", err.Error())
+	}
+}
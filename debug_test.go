@@ -0,0 +1,39 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDebugPage(t *testing.T) {
+	s := New()
+	s.Use(Recovery())
+	s.GET("/users/:id", HandlerFunc(func(c *Context) {
+		c.SetExtra("user", "admin")
+		panic("<boom>")
+	}))
+
+	if w := serve(s, "GET", "/users/1"); w.Body.String() != "Internal Server Error" {
+		t.Fatalf("expected the plain response in release mode, got %q", w.Body.String())
+	}
+
+	t.Setenv(ModeEnv, ReleaseMode)
+	s.SetMode(DebugMode)
+	if s.IsDebug() {
+		t.Fatal("expected the debug mode to be ignored in release mode")
+	}
+
+	t.Setenv(ModeEnv, "")
+	s.SetMode(DebugMode)
+	w := serve(s, "GET", "/users/1?tab=books")
+	body := w.Body.String()
+	for _, expect := range []string{"panic: &lt;boom&gt;", "debug_test.go", `panic(&#34;&lt;boom&gt;&#34;)`, "tab", "books", "admin"} {
+		if !strings.Contains(body, expect) {
+			t.Fatalf("expected %q in the debug page", expect)
+		}
+	}
+	if w.Code != http.StatusInternalServerError || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+}
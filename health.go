@@ -0,0 +1,198 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthCheck defines a named check of Health
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+	// Timeout of one run, default is 5s
+	Timeout time.Duration
+	// Critical checks fail the probe, the failures of the others only degrade it
+	Critical bool
+	// Liveness checks run for "/livez" too, all checks run for "/readyz"
+	Liveness bool
+}
+
+type healthResult struct {
+	err       error
+	duration  time.Duration
+	checkedAt time.Time
+}
+
+type healthCheck struct {
+	HealthCheck
+	mu     sync.Mutex // serializes the runs so that a check is not run concurrently
+	result *healthResult
+}
+
+// run returns the cached result if it is fresh, otherwise runs the check with its timeout
+func (hc *healthCheck) run(ctx context.Context, ttl time.Duration) healthResult {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if hc.result != nil && time.Since(hc.result.checkedAt) < ttl {
+		return *hc.result
+	}
+	ctx, cancel := context.WithTimeout(ctx, hc.Timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				done <- fmt.Errorf("panic: %v", err)
+			}
+		}()
+		done <- hc.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timeout after %s", hc.Timeout)
+	}
+	hc.result = &healthResult{err: err, duration: time.Since(start), checkedAt: time.Now()}
+	return *hc.result
+}
+
+// Health runs the checks of the liveness and readiness probes
+type Health struct {
+	mu            sync.RWMutex
+	checks        []*healthCheck
+	cacheTTL      time.Duration
+	shutdownDelay time.Duration
+	shuttingDown  atomic.Bool
+}
+
+func newHealth() *Health {
+	return &Health{cacheTTL: time.Second}
+}
+
+// Health returns the health subsystem of the server
+func (server *Server) Health() *Health {
+	return server.health
+}
+
+// AddCheck registers the check, the check of the same name is replaced
+func (h *Health) AddCheck(check HealthCheck) {
+	if check.Name == "" || check.Check == nil {
+		panic("the name and check of health check are required")
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = 5 * time.Second
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, hc := range h.checks {
+		if hc.Name == check.Name {
+			h.checks[i] = &healthCheck{HealthCheck: check}
+			return
+		}
+	}
+	h.checks = append(h.checks, &healthCheck{HealthCheck: check})
+}
+
+// SetCacheTTL sets how long the result of a check is reused, default is 1s
+func (h *Health) SetCacheTTL(ttl time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cacheTTL = ttl
+}
+
+// SetShutdownDelay sets how long Shutdown waits after readiness fails, so that the load balancers stop sending requests
+func (h *Health) SetShutdownDelay(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.shutdownDelay = d
+}
+
+// ShuttingDown reports whether the graceful shutdown began
+func (h *Health) ShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
+
+type healthCheckReport struct {
+	Status     string `json:"status"`
+	Critical   bool   `json:"critical"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+type healthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]healthCheckReport `json:"checks"`
+}
+
+// report runs the selected checks concurrently and reports "ok", "degraded" or "failing"
+func (h *Health) report(ctx context.Context, liveness bool) healthReport {
+	h.mu.RLock()
+	checks, ttl := h.checks, h.cacheTTL
+	h.mu.RUnlock()
+	report := healthReport{Status: "ok", Checks: make(map[string]healthCheckReport)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, hc := range checks {
+		if liveness && !hc.Liveness {
+			continue
+		}
+		wg.Add(1)
+		go func(hc *healthCheck) {
+			defer wg.Done()
+			result := hc.run(ctx, ttl)
+			item := healthCheckReport{Status: "ok", Critical: hc.Critical, DurationMs: result.duration.Milliseconds()}
+			mu.Lock()
+			defer mu.Unlock()
+			if result.err != nil {
+				item.Status, item.Error = "failing", result.err.Error()
+				if hc.Critical {
+					report.Status = "failing"
+				} else if report.Status == "ok" {
+					report.Status = "degraded"
+				}
+			}
+			report.Checks[hc.Name] = item
+		}(hc)
+	}
+	wg.Wait()
+	return report
+}
+
+func (h *Health) respond(c *Context, report healthReport) {
+	c.SetHeader("Cache-Control", "no-store")
+	code := http.StatusOK
+	if report.Status != "ok" && report.Status != "degraded" {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, report)
+}
+
+// Livez serves the liveness probe which runs the liveness checks only
+func (h *Health) Livez() Handler {
+	return HandlerFunc(func(c *Context) {
+		h.respond(c, h.report(c.Request.Context(), true))
+	})
+}
+
+// Readyz serves the readiness probe which runs all checks and fails once the graceful shutdown began
+func (h *Health) Readyz() Handler {
+	return HandlerFunc(func(c *Context) {
+		report := h.report(c.Request.Context(), false)
+		if h.ShuttingDown() {
+			report.Status = "shutting_down"
+		}
+		h.respond(c, report)
+	})
+}
+
+// Mount registers "/livez" and "/readyz" on the group
+func (h *Health) Mount(group *RouterGroup) {
+	group.GET("/livez", h.Livez())
+	group.GET("/readyz", h.Readyz())
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	s := New()
+	health := s.Health()
+	var dbCalls atomic.Int32
+	health.AddCheck(HealthCheck{Name: "db", Critical: true, Check: func(ctx context.Context) error {
+		dbCalls.Add(1)
+		return nil
+	}})
+	health.AddCheck(HealthCheck{Name: "cache", Check: func(ctx context.Context) error { return errors.New("miss") }})
+	health.AddCheck(HealthCheck{Name: "loop", Liveness: true, Timeout: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	health.Mount(s.RouterGroup)
+
+	probe := func(path string) (int, healthReport) {
+		w := serve(s, "GET", path)
+		var report healthReport
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, report
+	}
+	code, report := probe("/readyz")
+	if code != 200 || report.Status != "degraded" || report.Checks["cache"].Error != "miss" || report.Checks["loop"].Error != "timeout after 10ms" {
+		t.Fatalf("unexpected readiness %d %+v", code, report)
+	}
+	probe("/readyz")
+	if dbCalls.Load() != 1 {
+		t.Fatalf("expected the cached result, the check ran %d times", dbCalls.Load())
+	}
+	if code, report = probe("/livez"); code != 200 || len(report.Checks) != 1 {
+		t.Fatalf("unexpected liveness %d %+v", code, report)
+	}
+
+	health.AddCheck(HealthCheck{Name: "db", Critical: true, Check: func(ctx context.Context) error { return errors.New("down") }})
+	if code, report = probe("/readyz"); code != 503 || report.Status != "failing" {
+		t.Fatalf("unexpected readiness %d %+v", code, report)
+	}
+
+	health.AddCheck(HealthCheck{Name: "db", Critical: true, Check: func(ctx context.Context) error { return nil }})
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if code, report = probe("/readyz"); code != 503 || report.Status != "shutting_down" {
+		t.Fatalf("unexpected readiness after shutdown %d %+v", code, report)
+	}
+}
@@ -1,55 +1,304 @@
 package web
 
 import (
+	"math"
 	"net/http"
+	"reflect"
+	"strconv"
 	"sync"
 	"time"
 )
 
-// RateLimit is a middleware which limits the frequency of access to the same IP address
-func RateLimit(rate time.Duration) Handler {
-	var blackList sync.Map
-	return HandlerFunc(func(c *Context) {
-		remoteAddr := c.ClientIp()
-		if remoteAddr == "" {
-			c.Fail(http.StatusForbidden, "can not get client ip")
-			return
+// RateLimitConfig defines the config of RateLimitWithConfig
+type RateLimitConfig struct {
+	// KeyFunc returns the key which the requests are limited by, default is KeyByIP.
+	// The requests whose key is "" are rejected with 403.
+	KeyFunc func(c *Context) string
+	// Burst is the capacity of the token bucket of each key, default is 1
+	Burst int
+	// Interval is the time to refill one token, it must be positive if Store is nil
+	Interval time.Duration
+	// Store keeps the state of rate limit, default is a MemoryLimiterStore of TokenBucket made of Burst and Interval,
+	// and the requests are let through if the store fails
+	Store LimiterStore
+}
+
+// KeyByIP limits the requests by the client IP
+func KeyByIP(c *Context) string {
+	return c.ClientIp()
+}
+
+// KeyByHeader limits the requests by the value of the header, such as an API key
+func KeyByHeader(header string) func(c *Context) string {
+	return func(c *Context) string {
+		return c.GetHeader(header)
+	}
+}
+
+// KeyByJWTSubject limits the requests by the "sub" of the payload set by JwtConfirm
+func KeyByJWTSubject(c *Context) string {
+	obj := c.Extra("jwt")
+	if obj == nil {
+		return ""
+	}
+	if mp, ok := obj.(map[string]any); ok {
+		sub, _ := mp["sub"].(string)
+		return sub
+	}
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	if sub := v.FieldByName("Sub"); sub.IsValid() && sub.Kind() == reflect.String {
+		return sub.String()
+	}
+	return ""
+}
+
+// LimitResult is the result of taking a request from a LimiterStore
+type LimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // the time to wait until the next request is allowed
+	Reset      time.Duration // the time until the quota is fully restored
+}
+
+// LimiterStore keeps the state of rate limit. Take must check and update the state of the key atomically,
+// implement it on a shared store so that multiple instances share the same quota.
+type LimiterStore interface {
+	Take(key string, now time.Time) (LimitResult, error)
+}
+
+// LimitAlgorithm defines the algorithm of the in-memory LimiterStore
+type LimitAlgorithm int
+
+const (
+	// TokenBucket allows bursts of limit requests and refills a token every window/limit
+	TokenBucket LimitAlgorithm = iota
+	// SlidingWindowLog records the time of each request and allows limit requests in any window
+	SlidingWindowLog
+	// SlidingWindowCounter weights the counter of the previous window, it approximates SlidingWindowLog with less memory
+	SlidingWindowCounter
+)
+
+// limiterState is the state of a key, its take is called under the lock of the store
+type limiterState interface {
+	take(now time.Time) LimitResult
+	idle(now time.Time) bool
+}
+
+// MemoryLimiterStore keeps the state of rate limit in process memory, the idle keys are cleaned up lazily
+type MemoryLimiterStore struct {
+	mu        sync.Mutex
+	states    map[string]limiterState
+	newState  func(now time.Time) limiterState
+	window    time.Duration
+	lastSweep time.Time
+}
+
+// NewMemoryLimiterStore is the constructor of MemoryLimiterStore which allows limit requests per window
+func NewMemoryLimiterStore(algorithm LimitAlgorithm, limit int, window time.Duration) *MemoryLimiterStore {
+	if limit <= 0 || window <= 0 {
+		panic("the limit and window of rate limit must > 0")
+	}
+	store := &MemoryLimiterStore{states: make(map[string]limiterState), window: window, lastSweep: time.Now()}
+	switch algorithm {
+	case TokenBucket:
+		store.newState = func(now time.Time) limiterState {
+			return &tokenBucket{tokens: float64(limit), last: now, burst: float64(limit), interval: window / time.Duration(limit)}
 		}
-		if _, ok := blackList.Load(remoteAddr); !ok {
-			blackList.Store(remoteAddr, struct{}{})
-			go func() {
-				time.Sleep(rate)
-				blackList.Delete(remoteAddr)
-			}()
+	case SlidingWindowLog:
+		store.newState = func(time.Time) limiterState {
+			return &windowLog{limit: limit, window: window}
+		}
+	case SlidingWindowCounter:
+		store.newState = func(now time.Time) limiterState {
+			return &windowCounter{start: now, limit: limit, window: window}
+		}
+	default:
+		panic("unknown rate limit algorithm")
+	}
+	return store
+}
+
+func (s *MemoryLimiterStore) Take(key string, now time.Time) (LimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) > s.window {
+		for k, state := range s.states {
+			if state.idle(now) {
+				delete(s.states, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	state, has := s.states[key]
+	if !has {
+		state = s.newState(now)
+		s.states[key] = state
+	}
+	return state.take(now), nil
+}
+
+// tokenBucket computes the tokens lazily when taken instead of refilling them by goroutines
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time
+	burst    float64
+	interval time.Duration
+}
+
+func (b *tokenBucket) take(now time.Time) LimitResult {
+	b.tokens = math.Min(b.burst, b.tokens+float64(now.Sub(b.last))/float64(b.interval))
+	b.last = now
+	res := LimitResult{Allowed: b.tokens >= 1, Limit: int(b.burst)}
+	if res.Allowed {
+		b.tokens--
+	}
+	if b.tokens < 1 {
+		res.RetryAfter = time.Duration((1 - b.tokens) * float64(b.interval))
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((b.burst - b.tokens) * float64(b.interval))
+	return res
+}
+
+func (b *tokenBucket) idle(now time.Time) bool {
+	return now.Sub(b.last) > time.Duration(b.burst*float64(b.interval))
+}
+
+type windowLog struct {
+	times  []time.Time
+	limit  int
+	window time.Duration
+}
+
+func (l *windowLog) take(now time.Time) LimitResult {
+	i := 0
+	for i < len(l.times) && now.Sub(l.times[i]) >= l.window {
+		i++
+	}
+	l.times = l.times[i:]
+	res := LimitResult{Allowed: len(l.times) < l.limit, Limit: l.limit}
+	if res.Allowed {
+		l.times = append(l.times, now)
+	}
+	res.Remaining = l.limit - len(l.times)
+	if res.Remaining == 0 {
+		res.RetryAfter = l.times[0].Add(l.window).Sub(now)
+	}
+	if len(l.times) > 0 {
+		res.Reset = l.times[len(l.times)-1].Add(l.window).Sub(now)
+	}
+	return res
+}
+
+func (l *windowLog) idle(now time.Time) bool {
+	return len(l.times) == 0 || now.Sub(l.times[len(l.times)-1]) >= l.window
+}
+
+type windowCounter struct {
+	start  time.Time // the start of the current window
+	prev   int
+	curr   int
+	limit  int
+	window time.Duration
+}
+
+func (w *windowCounter) take(now time.Time) LimitResult {
+	if elapsed := now.Sub(w.start); elapsed >= w.window {
+		if elapsed >= 2*w.window {
+			w.prev = 0
 		} else {
-			c.Fail(http.StatusForbidden, "rate out of limit")
-			return
+			w.prev = w.curr
 		}
-		c.Next()
-	})
+		w.curr = 0
+		w.start = w.start.Add(elapsed / w.window * w.window)
+	}
+	ratio := 1 - float64(now.Sub(w.start))/float64(w.window)
+	estimate := func() float64 { return float64(w.prev)*ratio + float64(w.curr) }
+	res := LimitResult{Allowed: estimate()+1 <= float64(w.limit), Limit: w.limit}
+	if res.Allowed {
+		w.curr++
+	}
+	res.Remaining = max(0, int(float64(w.limit)-estimate()))
+	if res.Remaining == 0 {
+		free := float64(w.limit - 1 - w.curr)
+		if free < 0 {
+			// wait for the next window until the weight of the current counter drops enough
+			next := time.Duration((1 - float64(w.limit-1)/float64(w.curr)) * float64(w.window))
+			res.RetryAfter = w.start.Add(w.window).Sub(now) + next
+		} else {
+			// wait until the weight of the previous window drops enough
+			res.RetryAfter = time.Duration((ratio - free/float64(w.prev)) * float64(w.window))
+		}
+	}
+	res.Reset = w.start.Add(2 * w.window).Sub(now)
+	return res
 }
 
-// TrafficLimit is a middleware which uses token bucket algorithm for traffic restriction
-func TrafficLimit(tokenTotal int, rate time.Duration) Handler {
-	chg := make(chan struct{}, tokenTotal)
-	go func(ch chan<- struct{}) {
-		for i := 0; i < tokenTotal; i++ {
-			ch <- struct{}{}
+func (w *windowCounter) idle(now time.Time) bool {
+	return now.Sub(w.start) >= 2*w.window
+}
+
+// ceilSeconds formats the duration as the whole seconds rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// RateLimitWithConfig is a middleware which limits the requests of each key by the token bucket algorithm,
+// it sets the "RateLimit-*" headers and responds 429 with "Retry-After" when the bucket is empty
+func RateLimitWithConfig(config RateLimitConfig) Handler {
+	if config.KeyFunc == nil {
+		config.KeyFunc = KeyByIP
+	}
+	if config.Store == nil {
+		if config.Interval <= 0 {
+			panic("the interval of rate limit must > 0")
 		}
-		for {
-			time.Sleep(rate)
-			ch <- struct{}{}
+		if config.Burst <= 0 {
+			config.Burst = 1
 		}
-	}(chg)
+		config.Store = NewMemoryLimiterStore(TokenBucket, config.Burst, config.Interval*time.Duration(config.Burst))
+	}
 	return HandlerFunc(func(c *Context) {
-		var ch <-chan struct{}
-		ch = chg
-		select {
-		case <-ch:
+		key := config.KeyFunc(c)
+		if key == "" {
+			c.Fail(http.StatusForbidden, "can not get the rate limit key")
+			return
+		}
+		res, err := config.Store.Take(key, time.Now())
+		if err != nil {
+			c.Logger().Error("rate limit store error", "error", err.Error())
 			c.Next()
-		default:
-			c.Fail(http.StatusForbidden, "traffic congestion")
 			return
 		}
+		c.SetHeader("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.SetHeader("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.SetHeader("RateLimit-Reset", ceilSeconds(res.Reset))
+		if !res.Allowed {
+			c.SetHeader("Retry-After", ceilSeconds(res.RetryAfter))
+			c.Fail(http.StatusTooManyRequests, "rate out of limit")
+			return
+		}
+		c.Next()
+	})
+}
+
+// RateLimit is a middleware which limits the frequency of access to the same IP address
+func RateLimit(rate time.Duration) Handler {
+	return RateLimitWithConfig(RateLimitConfig{Interval: rate})
+}
+
+// TrafficLimit is a middleware which uses token bucket algorithm for traffic restriction
+func TrafficLimit(tokenTotal int, rate time.Duration) Handler {
+	return RateLimitWithConfig(RateLimitConfig{
+		KeyFunc:  func(*Context) string { return "*" },
+		Burst:    tokenTotal,
+		Interval: rate,
 	})
 }
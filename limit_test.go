@@ -0,0 +1,59 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiterStore(t *testing.T) {
+	now := time.Now()
+	for _, algorithm := range []LimitAlgorithm{TokenBucket, SlidingWindowLog, SlidingWindowCounter} {
+		store := NewMemoryLimiterStore(algorithm, 2, 2*time.Second)
+		for i, expect := range []bool{true, true, false} {
+			if res, _ := store.Take("a", now); res.Allowed != expect {
+				t.Fatalf("algorithm %d take %d: expected %v", algorithm, i, expect)
+			}
+		}
+		if res, _ := store.Take("b", now); !res.Allowed {
+			t.Fatalf("algorithm %d: expected the other key to be allowed", algorithm)
+		}
+		res, _ := store.Take("a", now.Add(500*time.Millisecond))
+		if res.Allowed || res.RetryAfter <= 0 || res.Remaining != 0 {
+			t.Fatalf("algorithm %d: expected to be limited with retry after, got %+v", algorithm, res)
+		}
+		if res, _ = store.Take("a", now.Add(500*time.Millisecond+res.RetryAfter+time.Millisecond)); !res.Allowed {
+			t.Fatalf("algorithm %d: expected to be allowed after retry after, got %+v", algorithm, res)
+		}
+		store.Take("c", now.Add(time.Minute))
+		if _, has := store.states["a"]; has {
+			t.Fatalf("algorithm %d: expected the idle key to be cleaned up", algorithm)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := New()
+	s.Use(RateLimitWithConfig(RateLimitConfig{KeyFunc: KeyByHeader("X-API-Key"), Burst: 1, Interval: time.Minute}))
+	s.GET("/x", HandlerFunc(func(c *Context) { c.String(200, "ok") }))
+	request := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/x", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		(&Engine{s}).ServeHTTP(w, req)
+		return w
+	}
+	if w := request("k1"); w.Code != 200 || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	if w := request("k1"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	if w := request("k2"); w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w := request(""); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", w.Code)
+	}
+}
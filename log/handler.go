@@ -0,0 +1,106 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	stdlog "log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/go-needle/log"
+)
+
+// Handler is a slog.Handler which writes the records through the logger of web,
+// so that log.Set still controls the level and output of them
+type Handler struct {
+	attrs  []slog.Attr
+	prefix string // the prefix of attribute keys made of the groups
+}
+
+// NewHandler is the constructor of Handler
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	var str, tail strings.Builder
+	str.WriteString(r.Message)
+	appendAttr := func(prefix string, a slog.Attr) {
+		value := a.Value.Resolve().String()
+		if strings.ContainsRune(value, '\n') {
+			// multi-line values such as stack traces are written after the message
+			tail.WriteString("\n" + value)
+			return
+		}
+		if value == "" || strings.ContainsAny(value, " \t\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		str.WriteString(" " + prefix + a.Key + "=" + value)
+	}
+	for _, a := range h.attrs {
+		appendAttr("", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(h.prefix, a)
+		return true
+	})
+	str.WriteString(tail.String())
+	write(r.Level, r.PC, str.String())
+	return nil
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	cp := &Handler{attrs: make([]slog.Attr, 0, len(h.attrs)+len(attrs)), prefix: h.prefix}
+	cp.attrs = append(cp.attrs, h.attrs...)
+	for _, a := range attrs {
+		cp.attrs = append(cp.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return cp
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// write prints a record like the logger of web does, but with the location of
+// the caller of slog instead of the one of the Handler
+func write(l slog.Level, pc uintptr, msg string) {
+	name, lv := "DEBUG", log.DebugLevel
+	switch {
+	case l >= slog.LevelError:
+		name, lv = "ERROR", log.ErrorLevel
+	case l >= slog.LevelWarn:
+		name, lv = "WARN", log.WarnLevel
+	case l >= slog.LevelInfo:
+		name, lv = "INFO", log.InfoLevel
+	}
+	if pc != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		msg = filepath.Base(f.File) + ":" + strconv.Itoa(f.Line) + ": " + msg
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if lv < minLevel {
+		return
+	}
+	prefix := "[" + name + "]"
+	if output == os.Stdout {
+		prefix = colors[name] + prefix + "\033[0m "
+	}
+	stdlog.New(output, prefix, stdlog.LstdFlags).Println(msg)
+}
+
+// colors of the prefixes on console, the same as the ones of the logger of web
+var colors = map[string]string{"DEBUG": "\033[31m", "INFO": "\033[34m", "WARN": "\033[33m", "ERROR": "\033[31m"}
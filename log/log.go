@@ -3,6 +3,8 @@ package log
 import (
 	"github.com/go-needle/log"
 	"io"
+	"os"
+	"sync"
 )
 
 // logger global
@@ -22,7 +24,20 @@ var (
 	Fatalf = logger.Fatalf
 )
 
+// the level and output set last, which the Handler writes its records with
+var (
+	mu       sync.Mutex
+	minLevel = log.DebugLevel
+	output   = io.Writer(os.Stdout)
+)
+
 // Set controls log level and output which is only for web
 func Set(level int, out io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
 	logger.Set(level, out)
+	minLevel = level
+	if out != nil {
+		output = out
+	}
 }
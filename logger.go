@@ -1,18 +1,248 @@
 package web
 
 import (
-	"github.com/go-needle/web/log"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// LogFormat defines the format of the access log
+type LogFormat int
+
+const (
+	// LogDefault is "[status] METHOD URI in latency"
+	LogDefault LogFormat = iota
+	// LogCommon is the Apache Common Log Format
+	LogCommon
+	// LogCombined is the Apache Combined Log Format
+	LogCombined
+	// LogJSON writes a LogEntry as a JSON line
+	LogJSON
+)
+
+// LogEntry is the information of a request in the access log
+type LogEntry struct {
+	Time      time.Time     `json:"time"`
+	Status    int           `json:"status"`
+	Method    string        `json:"method"`
+	URI       string        `json:"uri"`
+	Path      string        `json:"path"`
+	Route     string        `json:"route,omitempty"`
+	Proto     string        `json:"proto"`
+	ClientIP  string        `json:"clientIp"`
+	UserAgent string        `json:"userAgent,omitempty"`
+	Referer   string        `json:"referer,omitempty"`
+	Bytes     int           `json:"bytes"`
+	Latency   time.Duration `json:"latency"`
+	RequestID string        `json:"requestId,omitempty"`
+	Subject   string        `json:"subject,omitempty"` // the JWT subject set by JwtConfirm
+}
+
+// LoggerConfig defines the config of LoggerWithConfig
+type LoggerConfig struct {
+	Format LogFormat
+	// Template is the custom format which takes precedence over Format, such as "${ip} ${method} ${uri} ${status}".
+	// The fields are time, status, method, uri, path, route, proto, ip, user_agent, referer, bytes, latency,
+	// request_id and subject.
+	Template string
+	// Output is where the access log is written, default is the logger of Server which gets a structured record
+	// unless the format is LogCommon, LogCombined or a Template
+	Output io.Writer
+	// SkipPaths are the paths which are not logged, such as "/healthz"
+	SkipPaths []string
+	// SampleSuccess logs one of every SampleSuccess successful requests, 0 or 1 logs all.
+	// The requests with status >= 400 are always logged.
+	SampleSuccess int
+}
+
+// logTemplate is the parsed custom template, odd segments are the field names
+type logTemplate []string
+
+func parseLogTemplate(tpl string) logTemplate {
+	var segments logTemplate
+	for {
+		start := strings.Index(tpl, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(tpl[start:], '}')
+		if end < 0 {
+			break
+		}
+		segments = append(segments, tpl[:start], tpl[start+2:start+end])
+		tpl = tpl[start+end+1:]
+	}
+	return append(segments, tpl)
+}
+
+func (entry *LogEntry) field(name string) string {
+	switch name {
+	case "time":
+		return entry.Time.Format(time.RFC3339)
+	case "status":
+		return strconv.Itoa(entry.Status)
+	case "method":
+		return entry.Method
+	case "uri":
+		return entry.URI
+	case "path":
+		return entry.Path
+	case "route":
+		return entry.Route
+	case "proto":
+		return entry.Proto
+	case "ip":
+		return entry.ClientIP
+	case "user_agent":
+		return entry.UserAgent
+	case "referer":
+		return entry.Referer
+	case "bytes":
+		return strconv.Itoa(entry.Bytes)
+	case "latency":
+		return entry.Latency.String()
+	case "request_id":
+		return entry.RequestID
+	case "subject":
+		return entry.Subject
+	}
+	return ""
+}
+
+func (tpl logTemplate) format(entry *LogEntry) string {
+	var str strings.Builder
+	for i, segment := range tpl {
+		if i%2 == 1 {
+			str.WriteString(entry.field(segment))
+		} else {
+			str.WriteString(segment)
+		}
+	}
+	return str.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (entry *LogEntry) common() string {
+	bytes := "-"
+	if entry.Bytes > 0 {
+		bytes = strconv.Itoa(entry.Bytes)
+	}
+	return orDash(entry.ClientIP) + " - " + orDash(entry.Subject) + " [" + entry.Time.Format("02/Jan/2006:15:04:05 -0700") + "] \"" +
+		entry.Method + " " + entry.URI + " " + entry.Proto + "\" " + strconv.Itoa(entry.Status) + " " + bytes
+}
+
+func (entry *LogEntry) combined() string {
+	return entry.common() + " " + strconv.Quote(orDash(entry.Referer)) + " " + strconv.Quote(orDash(entry.UserAgent))
+}
+
+func (entry *LogEntry) defaultFormat() string {
+	if entry.RequestID != "" {
+		return "[" + strconv.Itoa(entry.Status) + "] " + entry.Method + " " + entry.URI + " in " + entry.Latency.String() + " (request id: " + entry.RequestID + ")"
+	}
+	return "[" + strconv.Itoa(entry.Status) + "] " + entry.Method + " " + entry.URI + " in " + entry.Latency.String()
+}
+
+// attrs returns the attributes of the structured record, the request attributes of Context.Logger are excluded
+func (entry *LogEntry) attrs() []any {
+	attrs := []any{"status", entry.Status, "uri", entry.URI, "latency", entry.Latency, "ip", entry.ClientIP, "bytes", entry.Bytes}
+	if entry.UserAgent != "" {
+		attrs = append(attrs, "user_agent", entry.UserAgent)
+	}
+	if entry.Referer != "" {
+		attrs = append(attrs, "referer", entry.Referer)
+	}
+	if entry.Subject != "" {
+		attrs = append(attrs, "subject", entry.Subject)
+	}
+	return attrs
+}
+
 // Logger is a middleware which defines to log every http request
 func Logger() Handler {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+// LoggerWithConfig is a middleware which writes the access log in the configured format
+func LoggerWithConfig(config LoggerConfig) Handler {
+	skip := make(map[string]struct{}, len(config.SkipPaths))
+	for _, path := range config.SkipPaths {
+		skip[path] = struct{}{}
+	}
+	var tpl logTemplate
+	if config.Template != "" {
+		tpl = parseLogTemplate(config.Template)
+	}
+	var mu sync.Mutex
+	write := func(c *Context, line string) {
+		if config.Output == nil {
+			c.server.logger.Info(line)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = io.WriteString(config.Output, line+"\n")
+	}
+	var successCount atomic.Uint64
 	return HandlerFunc(func(c *Context) {
+		if _, has := skip[c.Path]; has {
+			c.Next()
+			return
+		}
 		// Start timer
 		t := time.Now()
 		// Process request
 		c.Next()
 		// Calculate resolution time
-		log.Infof("[%d] %s %s in %v", c.StatusCode, c.Method, c.Request.RequestURI, time.Since(t))
+		latency := time.Since(t)
+		status := c.Writer.Status()
+		if config.SampleSuccess > 1 && status < 400 && (successCount.Add(1)-1)%uint64(config.SampleSuccess) != 0 {
+			return
+		}
+		entry := &LogEntry{
+			Time:      t,
+			Status:    status,
+			Method:    c.Method,
+			URI:       c.Request.RequestURI,
+			Path:      c.Path,
+			Route:     c.FullPath(),
+			Proto:     c.Request.Proto,
+			ClientIP:  c.ClientIp(),
+			UserAgent: c.Request.UserAgent(),
+			Referer:   c.Request.Referer(),
+			Bytes:     c.Writer.Size(),
+			Latency:   latency,
+			RequestID: c.RequestID(),
+			Subject:   KeyByJWTSubject(c),
+		}
+		switch {
+		case tpl != nil:
+			write(c, tpl.format(entry))
+		case config.Format == LogCommon:
+			write(c, entry.common())
+		case config.Format == LogCombined:
+			write(c, entry.combined())
+		case config.Output == nil:
+			// the logger of server gets the structured record
+			c.Logger().Info("http request", entry.attrs()...)
+		case config.Format == LogJSON:
+			data, err := json.Marshal(entry)
+			if err != nil {
+				c.Logger().Error("access log encoding error", "error", err.Error())
+				return
+			}
+			write(c, string(data))
+		default:
+			write(c, entry.defaultFormat())
+		}
 	})
 }
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLoggerWithConfig(t *testing.T) {
+	var buf bytes.Buffer
+	newServer := func(config LoggerConfig) *Server {
+		buf.Reset()
+		config.Output = &buf
+		s := New()
+		s.Use(LoggerWithConfig(config))
+		s.GET("/users/:id", HandlerFunc(func(c *Context) { c.String(200, "hello") }))
+		s.GET("/healthz", HandlerFunc(func(c *Context) { c.String(200, "ok") }))
+		return s
+	}
+	request := func(s *Server, path string) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("User-Agent", "test-agent")
+		(&Engine{s}).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	s := newServer(LoggerConfig{Format: LogCombined})
+	request(s, "/users/1")
+	combined := regexp.MustCompile(`^192\.0\.2\.1 - - \[.+\] "GET /users/1 HTTP/1\.1" 200 5 "-" "test-agent"\n$`)
+	if !combined.MatchString(buf.String()) {
+		t.Fatalf("unexpected combined log %q", buf.String())
+	}
+
+	s = newServer(LoggerConfig{Format: LogJSON, SkipPaths: []string{"/healthz"}})
+	request(s, "/healthz")
+	request(s, "/users/2")
+	entry := &LogEntry{}
+	if err := json.Unmarshal(buf.Bytes(), entry); err != nil || entry.Route != "/users/:id" || entry.Path != "/users/2" {
+		t.Fatalf("unexpected json log %q", buf.String())
+	}
+
+	s = newServer(LoggerConfig{Template: "${method} ${route} ${status} ${bytes}", SampleSuccess: 2})
+	for i := 0; i < 4; i++ {
+		request(s, "/users/3")
+	}
+	request(s, "/missing")
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 || lines[0] != "GET /users/:id 200 5" || !strings.HasPrefix(lines[2], "GET  404") {
+		t.Fatalf("unexpected template log %q", buf.String())
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	s := New()
+	s.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	s.Use(RequestID(), Logger())
+	s.GET("/users/:id", HandlerFunc(func(c *Context) {
+		c.Logger().Info("loading user")
+		c.String(200, "ok")
+	}))
+	s.GET("/dup", HandlerFunc(func(c *Context) {}))
+	s.GET("/dup", HandlerFunc(func(c *Context) {})) // route coverage warning
+
+	serve(s, "GET", "/users/1")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 records, got %q", buf.String())
+	}
+	records := make([]map[string]any, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if records[0]["level"] != "WARN" || records[0]["pattern"] != "/dup" {
+		t.Fatalf("unexpected warning %v", records[0])
+	}
+	if records[1]["msg"] != "loading user" || records[1]["route"] != "/users/:id" || records[1]["request_id"] == nil {
+		t.Fatalf("unexpected request record %v", records[1])
+	}
+	if records[2]["msg"] != "http request" || records[2]["status"] != float64(200) || records[2]["request_id"] != records[1]["request_id"] {
+		t.Fatalf("unexpected access record %v", records[2])
+	}
+}
@@ -0,0 +1,377 @@
+package web
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Collector writes its metrics in the Prometheus text exposition format
+type Collector interface {
+	// Names returns the names of the metric families written by Collect
+	Names() []string
+	Collect(w io.Writer)
+}
+
+// Registry holds the collectors which are exposed by its handler
+type Registry struct {
+	mu          sync.RWMutex
+	collectors  []Collector
+	names       map[string]struct{}
+	httpMetrics map[string]*httpMetrics // the metrics of MetricsWithConfig by namespace
+}
+
+// NewRegistry is the constructor of Registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]struct{}), httpMetrics: make(map[string]*httpMetrics)}
+}
+
+// DefaultRegistry is the registry used by Metrics and MetricsHandler
+var DefaultRegistry = NewRegistry()
+
+// Register adds the collectors to the registry, it panics if a metric family name is registered already
+// because the scrapers reject the duplicated families
+func (r *Registry) Register(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.register(collectors...)
+}
+
+func (r *Registry) register(collectors ...Collector) {
+	for _, collector := range collectors {
+		for _, name := range collector.Names() {
+			if _, has := r.names[name]; has {
+				panic("the metric " + name + " is registered already")
+			}
+		}
+	}
+	for _, collector := range collectors {
+		for _, name := range collector.Names() {
+			r.names[name] = struct{}{}
+		}
+	}
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// Handler serves the metrics of all collectors in the Prometheus text exposition format
+func (r *Registry) Handler() Handler {
+	return HandlerFunc(func(c *Context) {
+		r.mu.RLock()
+		collectors := r.collectors
+		r.mu.RUnlock()
+		c.SetHeader("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		w := bufio.NewWriter(c.Writer)
+		for _, collector := range collectors {
+			collector.Collect(w)
+		}
+		if err := w.Flush(); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// MetricsHandler serves the metrics of DefaultRegistry, mount it such as s.GET("/metrics", web.MetricsHandler())
+func MetricsHandler() Handler {
+	return DefaultRegistry.Handler()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatLabels formats the labels such as {method="GET",status="200"}, extra is appended as is
+func formatLabels(names, values []string, extra string) string {
+	if len(names) == 0 && extra == "" {
+		return ""
+	}
+	var str strings.Builder
+	str.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			str.WriteByte(',')
+		}
+		str.WriteString(name + `="` + labelValueEscaper.Replace(values[i]) + `"`)
+	}
+	if extra != "" {
+		if len(names) > 0 {
+			str.WriteByte(',')
+		}
+		str.WriteString(extra)
+	}
+	str.WriteByte('}')
+	return str.String()
+}
+
+// metricVec keeps the series of a metric by the label values
+type metricVec[T any] struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+	mu     sync.Mutex
+	series map[string]*metricSeries[T]
+	newT   func() T
+}
+
+type metricSeries[T any] struct {
+	labelValues []string
+	value       T
+}
+
+func newMetricVec[T any](name, help, typ string, labels []string, newT func() T) *metricVec[T] {
+	return &metricVec[T]{name: name, help: help, typ: typ, labels: labels, series: make(map[string]*metricSeries[T]), newT: newT}
+}
+
+// with calls fn with the series of the label values under the lock
+func (m *metricVec[T]) with(labelValues []string, fn func(value *T)) {
+	if len(labelValues) != len(m.labels) {
+		panic("the count of label values of " + m.name + " must be " + strconv.Itoa(len(m.labels)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, has := m.series[key]
+	if !has {
+		s = &metricSeries[T]{append([]string(nil), labelValues...), m.newT()}
+		m.series[key] = s
+	}
+	fn(&s.value)
+}
+
+// collect writes the header and each series in the order of label values
+func (m *metricVec[T]) collect(w io.Writer, write func(labelValues []string, value *T)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, _ = io.WriteString(w, "# HELP "+m.name+" "+strings.ReplaceAll(m.help, "\n", `\n`)+"\n# TYPE "+m.name+" "+m.typ+"\n")
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		write(m.series[key].labelValues, &m.series[key].value)
+	}
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	vec *metricVec[float64]
+}
+
+// NewCounterVec is the constructor of CounterVec
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{newMetricVec(name, help, "counter", labels, func() float64 { return 0 })}
+}
+
+// Add adds v which must not be negative to the counter of the label values
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("counter cannot decrease")
+	}
+	c.vec.with(labelValues, func(value *float64) { *value += v })
+}
+
+// Inc increases the counter of the label values by 1
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Names returns the name of the CounterVec
+func (c *CounterVec) Names() []string {
+	return []string{c.vec.name}
+}
+
+// Collect writes the series of the CounterVec
+func (c *CounterVec) Collect(w io.Writer) {
+	c.vec.collect(w, func(labelValues []string, value *float64) {
+		_, _ = io.WriteString(w, c.vec.name+formatLabels(c.vec.labels, labelValues, "")+" "+formatFloat(*value)+"\n")
+	})
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct {
+	vec *metricVec[float64]
+}
+
+// NewGaugeVec is the constructor of GaugeVec
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{newMetricVec(name, help, "gauge", labels, func() float64 { return 0 })}
+}
+
+// Set sets the gauge of the label values
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	g.vec.with(labelValues, func(value *float64) { *value = v })
+}
+
+// Add adds v to the gauge of the label values
+func (g *GaugeVec) Add(v float64, labelValues ...string) {
+	g.vec.with(labelValues, func(value *float64) { *value += v })
+}
+
+// Names returns the name of the GaugeVec
+func (g *GaugeVec) Names() []string {
+	return []string{g.vec.name}
+}
+
+// Collect writes the series of the GaugeVec
+func (g *GaugeVec) Collect(w io.Writer) {
+	g.vec.collect(w, func(labelValues []string, value *float64) {
+		_, _ = io.WriteString(w, g.vec.name+formatLabels(g.vec.labels, labelValues, "")+" "+formatFloat(*value)+"\n")
+	})
+}
+
+type histogram struct {
+	counts []uint64 // the count of each bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	vec     *metricVec[histogram]
+	buckets []float64
+}
+
+// DefaultBuckets are the buckets of the request duration in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// NewHistogramVec is the constructor of HistogramVec, buckets are the upper bounds in increasing order
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !sort.Float64sAreSorted(buckets) {
+		panic("the buckets of histogram must be in increasing order")
+	}
+	buckets = append([]float64(nil), buckets...)
+	return &HistogramVec{newMetricVec(name, help, "histogram", labels, func() histogram {
+		return histogram{counts: make([]uint64, len(buckets))}
+	}), buckets}
+}
+
+// Observe adds v to the histogram of the label values
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.vec.with(labelValues, func(value *histogram) {
+		if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+			value.counts[i]++
+		}
+		value.sum += v
+		value.count++
+	})
+}
+
+// Names returns the name of the HistogramVec
+func (h *HistogramVec) Names() []string {
+	return []string{h.vec.name}
+}
+
+// Collect writes the series of the HistogramVec
+func (h *HistogramVec) Collect(w io.Writer) {
+	name, labels := h.vec.name, h.vec.labels
+	h.vec.collect(w, func(labelValues []string, value *histogram) {
+		cumulative := uint64(0)
+		for i, bound := range h.buckets {
+			cumulative += value.counts[i]
+			_, _ = io.WriteString(w, name+"_bucket"+formatLabels(labels, labelValues, `le="`+formatFloat(bound)+`"`)+" "+strconv.FormatUint(cumulative, 10)+"\n")
+		}
+		_, _ = io.WriteString(w, name+"_bucket"+formatLabels(labels, labelValues, `le="+Inf"`)+" "+strconv.FormatUint(value.count, 10)+"\n")
+		_, _ = io.WriteString(w, name+"_sum"+formatLabels(labels, labelValues, "")+" "+formatFloat(value.sum)+"\n")
+		_, _ = io.WriteString(w, name+"_count"+formatLabels(labels, labelValues, "")+" "+strconv.FormatUint(value.count, 10)+"\n")
+	})
+}
+
+// MetricsConfig defines the config of MetricsWithConfig
+type MetricsConfig struct {
+	Registry    *Registry // default is DefaultRegistry
+	Namespace   string    // the prefix of metric names, default is "http"
+	Buckets     []float64 // the buckets of request duration in seconds, default is DefaultBuckets
+	SizeBuckets []float64 // the buckets of response size in bytes, default is 100B to 100MB by the power of 10
+}
+
+// Metrics is a middleware which records the request metrics into DefaultRegistry
+func Metrics() Handler {
+	return MetricsWithConfig(MetricsConfig{})
+}
+
+type httpMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+	size     *HistogramVec
+	inFlight *GaugeVec
+}
+
+// httpMetricsOf returns the metrics of the namespace in the registry, they are shared by all Metrics middlewares
+// of the same registry and namespace, so that the buckets of the first config are used
+func (r *Registry) httpMetricsOf(config MetricsConfig) *httpMetrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, has := r.httpMetrics[config.Namespace]; has {
+		return m
+	}
+	ns := config.Namespace + "_"
+	m := &httpMetrics{
+		requests: NewCounterVec(ns+"requests_total", "Total number of HTTP requests.", "method", "route", "status"),
+		duration: NewHistogramVec(ns+"request_duration_seconds", "HTTP request latency in seconds.", config.Buckets, "method", "route", "status"),
+		size:     NewHistogramVec(ns+"response_size_bytes", "HTTP response size in bytes.", config.SizeBuckets, "method", "route", "status"),
+		inFlight: NewGaugeVec(ns+"requests_in_flight", "Number of HTTP requests being served."),
+	}
+	r.register(m.requests, m.duration, m.size, m.inFlight)
+	r.httpMetrics[config.Namespace] = m
+	return m
+}
+
+// MetricsWithConfig is a middleware which records the count, latency, in-flight requests and response size,
+// labeled by method, route pattern and status. The panics are recorded as 500 and passed on.
+func MetricsWithConfig(config MetricsConfig) Handler {
+	if config.Registry == nil {
+		config.Registry = DefaultRegistry
+	}
+	if config.Namespace == "" {
+		config.Namespace = "http"
+	}
+	if config.Buckets == nil {
+		config.Buckets = DefaultBuckets
+	}
+	if config.SizeBuckets == nil {
+		config.SizeBuckets = []float64{100, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8}
+	}
+	m := config.Registry.httpMetricsOf(config)
+	return HandlerFunc(func(c *Context) {
+		start := time.Now()
+		m.inFlight.Add(1)
+		defer func() {
+			err := recover()
+			m.inFlight.Add(-1)
+			route := c.fullPath
+			if route == "" {
+				route = "unmatched"
+			}
+			code := c.Writer.Status()
+			if err != nil {
+				code = http.StatusInternalServerError
+			}
+			status := strconv.Itoa(code)
+			m.requests.Inc(c.Method, route, status)
+			m.duration.Observe(time.Since(start).Seconds(), c.Method, route, status)
+			m.size.Observe(float64(c.Writer.Size()), c.Method, route, status)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		c.Next()
+	})
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := NewRegistry()
+	jobs := NewCounterVec("jobs_total", "Total jobs.", "queue")
+	registry.Register(jobs)
+	jobs.Inc(`mail"s`)
+
+	s := New()
+	s.Use(Recovery(), MetricsWithConfig(MetricsConfig{Registry: registry, Buckets: []float64{1}}))
+	s.GET("/users/:id", HandlerFunc(func(c *Context) { c.String(200, "hello") }))
+	s.GET("/panic", HandlerFunc(func(c *Context) { panic("boom") }))
+	serve(s, "GET", "/users/1")
+	serve(s, "GET", "/missing")
+	serve(s, "GET", "/panic")
+
+	// the second server shares the metrics of the registry
+	other := New()
+	other.Use(MetricsWithConfig(MetricsConfig{Registry: registry}))
+	other.GET("/users/:id", HandlerFunc(func(c *Context) { c.String(200, "hello") }))
+	serve(other, "GET", "/users/2")
+
+	admin := New()
+	admin.GET("/metrics", registry.Handler())
+	w := serve(admin, "GET", "/metrics")
+	if w.Header().Get("Content-Type") != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE http_requests_total counter",
+		`http_requests_total{method="GET",route="/users/:id",status="200"} 2`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_requests_total{method="GET",route="/panic",status="500"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/users/:id",status="200",le="+Inf"} 2`,
+		`http_request_duration_seconds_count{method="GET",route="/users/:id",status="200"} 2`,
+		`http_response_size_bytes_sum{method="GET",route="/users/:id",status="200"} 10`,
+		"http_requests_in_flight 0",
+		`jobs_total{queue="mail\"s"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, body)
+		}
+	}
+	if n := strings.Count(body, "# TYPE http_requests_total "); n != 1 {
+		t.Errorf("expected one family of http_requests_total, got %d", n)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected the duplicated metric to panic")
+		}
+	}()
+	registry.Register(NewGaugeVec("jobs_total", "Duplicated."))
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+// WrapHTTP adapts the http.Handler of the standard library to Handler
+func WrapHTTP(h http.Handler) Handler {
+	return HandlerFunc(func(c *Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	})
+}
+
+// LocalhostOnly is a middleware which rejects the requests whose peer is not a loopback address,
+// the forwarded headers are not trusted
+func LocalhostOnly() Handler {
+	return HandlerFunc(func(c *Context) {
+		host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+		if err != nil {
+			host = c.Request.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			c.Fail(http.StatusForbidden, "403 FORBIDDEN")
+			return
+		}
+		c.Next()
+	})
+}
+
+var processStart = time.Now()
+
+// runtimeStats serves the goroutines, GC and memory statistics as JSON
+func runtimeStats(c *Context) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	lastGC := ""
+	if m.LastGC > 0 {
+		lastGC = time.Unix(0, int64(m.LastGC)).UTC().Format(time.RFC3339Nano)
+	}
+	c.JSON(http.StatusOK, H{
+		"go_version": runtime.Version(),
+		"uptime":     time.Since(processStart).String(),
+		"goroutines": runtime.NumGoroutine(),
+		"num_cpu":    runtime.NumCPU(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"cgo_calls":  runtime.NumCgoCall(),
+		"gc": H{
+			"num_gc":         m.NumGC,
+			"num_forced_gc":  m.NumForcedGC,
+			"pause_total_ns": m.PauseTotalNs,
+			"last_pause_ns":  m.PauseNs[(m.NumGC+255)%256],
+			"last_gc":        lastGC,
+			"next_gc":        m.NextGC,
+			"cpu_fraction":   m.GCCPUFraction,
+		},
+		"memstats": H{
+			"alloc":         m.Alloc,
+			"total_alloc":   m.TotalAlloc,
+			"sys":           m.Sys,
+			"mallocs":       m.Mallocs,
+			"frees":         m.Frees,
+			"heap_alloc":    m.HeapAlloc,
+			"heap_sys":      m.HeapSys,
+			"heap_idle":     m.HeapIdle,
+			"heap_inuse":    m.HeapInuse,
+			"heap_released": m.HeapReleased,
+			"heap_objects":  m.HeapObjects,
+			"stack_inuse":   m.StackInuse,
+			"stack_sys":     m.StackSys,
+		},
+	})
+}
+
+// Pprof registers the pprof profiles under "/debug/pprof/", expvar at "/debug/vars" and the runtime stats
+// at "/debug/runtime" in the group. The guards protect all of them, LocalhostOnly is used if no guard is given.
+// Visit the index with the trailing slash so that its relative links resolve.
+func (group *RouterGroup) Pprof(guards ...Handler) *RouterGroup {
+	if len(guards) == 0 {
+		guards = []Handler{LocalhostOnly()}
+	}
+	debug := group.Group("/debug").Use(guards...)
+	debug.GET("/pprof", WrapHTTP(http.HandlerFunc(pprof.Index)))
+	debug.GET("/pprof/cmdline", WrapHTTP(http.HandlerFunc(pprof.Cmdline)))
+	debug.GET("/pprof/profile", WrapHTTP(http.HandlerFunc(pprof.Profile)))
+	debug.GET("/pprof/symbol", WrapHTTP(http.HandlerFunc(pprof.Symbol)))
+	debug.POST("/pprof/symbol", WrapHTTP(http.HandlerFunc(pprof.Symbol)))
+	debug.GET("/pprof/trace", WrapHTTP(http.HandlerFunc(pprof.Trace)))
+	debug.GET("/pprof/:name", HandlerFunc(func(c *Context) {
+		pprof.Handler(c.Param("name")).ServeHTTP(c.Writer, c.Request)
+	}))
+	debug.GET("/vars", WrapHTTP(expvar.Handler()))
+	debug.GET("/runtime", HandlerFunc(runtimeStats))
+	return debug
+}
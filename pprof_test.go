@@ -0,0 +1,54 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPprof(t *testing.T) {
+	s := New()
+	s.Pprof()
+	request := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		(&Engine{s}).ServeHTTP(w, req)
+		return w
+	}
+	if w := request("/debug/runtime", "192.0.2.1:1234"); w.Code != 403 {
+		t.Fatalf("expected 403 for remote peer, got %d", w.Code)
+	}
+	for _, path := range []string{"//debug/runtime", "//debug/pprof/cmdline", "/x/../debug/vars", "/debug//pprof/"} {
+		if w := request(path, "192.0.2.1:1234"); w.Code != 403 {
+			t.Fatalf("expected 403 for %s from remote peer, got %d", path, w.Code)
+		}
+	}
+	w := request("/debug/runtime", "127.0.0.1:1234")
+	var stats map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || stats["goroutines"].(float64) < 1 || stats["memstats"] == nil {
+		t.Fatalf("unexpected runtime stats %d %s", w.Code, w.Body.String())
+	}
+	if w = request("/debug/pprof/", "[::1]:1234"); w.Code != 200 || !strings.Contains(w.Body.String(), "goroutine") {
+		t.Fatalf("unexpected index %d", w.Code)
+	}
+	if w = request("/debug/pprof/heap?debug=1", "127.0.0.1:1234"); w.Code != 200 || !strings.Contains(w.Body.String(), "heap profile") {
+		t.Fatalf("unexpected heap profile %d", w.Code)
+	}
+	if w = request("/debug/vars", "127.0.0.1:1234"); w.Code != 200 || !strings.Contains(w.Body.String(), "memstats") {
+		t.Fatalf("unexpected expvar %d", w.Code)
+	}
+
+	guarded := New()
+	guarded.Group("/admin").Pprof(HandlerFunc(func(c *Context) {
+		if c.GetHeader("X-Token") != "secret" {
+			c.Fail(401, "401 UNAUTHORIZED")
+			return
+		}
+		c.Next()
+	}))
+	if w = serve(guarded, "GET", "/admin/debug/runtime"); w.Code != 401 {
+		t.Fatalf("expected the guard to reject, got %d", w.Code)
+	}
+}
@@ -1,37 +1,117 @@
 package web
 
 import (
+	"errors"
 	"fmt"
-	"github.com/go-needle/web/log"
 	"net/http"
 	"runtime"
 	"strings"
+	"syscall"
 )
 
-func trace(message string) string {
-	var pcs [32]uintptr
-	n := runtime.Callers(3, pcs[:]) // skip first 3 caller
+// RecoveryConfig defines the config of RecoveryWithConfig
+type RecoveryConfig struct {
+	// Handler responds to the panic such as rendering JSON or HTML, default is a 500 "Internal Server Error"
+	Handler func(c *Context, err any)
+	// Reporter is called with the panic and the stack, such as sending them to an error tracker
+	Reporter func(c *Context, err any, stack []byte)
+	// StackSize limits the bytes of the stack, default is 8KB
+	StackSize int
+}
+
+// stack returns the stack of the current goroutine, it is truncated to size bytes
+func stack(size int) []byte {
+	buf := make([]byte, size)
+	return buf[:runtime.Stack(buf, false)]
+}
 
-	var str strings.Builder
-	str.WriteString(message + "\nTraceback:")
-	for _, pc := range pcs[:n] {
-		fn := runtime.FuncForPC(pc)
-		file, line := fn.FileLine(pc)
-		str.WriteString(fmt.Sprintf("\n\t%s:%d", file, line))
+// callers returns the program counters of the current goroutine, skip is the same as runtime.Callers
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 64)
+	return pcs[:runtime.Callers(skip, pcs)]
+}
+
+// forkedPanic carries the panic of a handler goroutine such as the one of Timeout to the middleware goroutine,
+// so that the stack where the panic happened is reported instead of the one where it is panicked again
+type forkedPanic struct {
+	value any
+	stack []byte
+	pcs   []uintptr
+}
+
+func (p *forkedPanic) String() string {
+	return fmt.Sprintf("%v", p.value)
+}
+
+// isBrokenPipe reports whether the panic is caused by a connection which has been closed by the client
+func isBrokenPipe(err any) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
 	}
-	return str.String()
+	if errors.Is(e, syscall.EPIPE) || errors.Is(e, syscall.ECONNRESET) {
+		return true
+	}
+	msg := strings.ToLower(e.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
+func defaultRecoveryHandler(c *Context, _ any) {
+	c.Fail(http.StatusInternalServerError, "Internal Server Error")
 }
 
 // Recovery is a middleware which defines to prevent panic from causing HTTP service termination
 func Recovery() Handler {
+	return RecoveryWithConfig(RecoveryConfig{})
+}
+
+// RecoveryWithConfig is a middleware which recovers from panics with the custom response and reporter.
+// Without a custom handler, the panic is rendered as a debug page if the server is in debug mode.
+// The http.ErrAbortHandler is panicked again to abort the response, and nothing is written to a broken connection
+// whose request is recorded as StatusClientClosedRequest.
+func RecoveryWithConfig(config RecoveryConfig) Handler {
+	// the debug page takes the place of the default handler in debug mode
+	useDebugPage := config.Handler == nil
+	if config.Handler == nil {
+		config.Handler = defaultRecoveryHandler
+	}
+	if config.StackSize <= 0 {
+		config.StackSize = 8 << 10
+	}
 	return HandlerFunc(func(c *Context) {
 		defer func() {
-			if err := recover(); err != nil {
-				message := fmt.Sprintf("%s", err)
-				c.Fail(http.StatusInternalServerError, "Internal Server Error")
-				log.Errorf("[%d] %s %s Internal Server Error", http.StatusInternalServerError, c.Method, c.Request.RequestURI)
-				log.Errorf("\033[31m%s\n\n\033[0m", trace(message))
+			err := recover()
+			if err == nil {
+				return
+			}
+			// skip runtime.Callers, callers and this function
+			st, pcs := []byte(nil), callers(3)
+			if p, ok := err.(*forkedPanic); ok {
+				err, st, pcs = p.value, p.stack[:min(len(p.stack), config.StackSize)], p.pcs
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			message := fmt.Sprintf("%v", err)
+			if isBrokenPipe(err) {
+				c.Logger().Error("Broken connection", "error", message)
+				c.writer.abort(StatusClientClosedRequest)
+				c.Abort()
+				return
+			}
+			if st == nil {
+				st = stack(config.StackSize)
+			}
+			if config.Reporter != nil {
+				config.Reporter(c, err, st)
+			}
+			c.Logger().Error("Internal Server Error", "status", http.StatusInternalServerError, "error", message, "stack", string(st))
+			c.Abort()
+			if useDebugPage && c.server.IsDebug() {
+				renderDebugPage(c, err, pcs)
+				return
 			}
+			config.Handler(c, err)
 		}()
 		c.Next()
 	})
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// headerRecorder records whether the headers were written
+type headerRecorder struct {
+	*httptest.ResponseRecorder
+	headerWritten bool
+}
+
+func (r *headerRecorder) WriteHeader(code int) {
+	r.headerWritten = true
+	r.ResponseRecorder.WriteHeader(code)
+}
+
+func TestRecoveryWithConfig(t *testing.T) {
+	var reported any
+	var reportedStack []byte
+	var status int
+	s := New()
+	s.Use(HandlerFunc(func(c *Context) {
+		c.Next()
+		status = c.Writer.Status()
+	}), RecoveryWithConfig(RecoveryConfig{
+		Handler: func(c *Context, err any) {
+			c.JSON(http.StatusInternalServerError, H{"error": "internal"})
+		},
+		Reporter: func(c *Context, err any, stack []byte) {
+			reported, reportedStack = err, stack
+		},
+		StackSize: 256,
+	}))
+	s.GET("/panic", HandlerFunc(func(c *Context) { panic("boom") }))
+	s.GET("/broken", HandlerFunc(func(c *Context) {
+		panic(errors.Join(errors.New("write tcp"), syscall.EPIPE))
+	}))
+	s.GET("/abort", HandlerFunc(func(c *Context) { panic(http.ErrAbortHandler) }))
+
+	w := serve(s, "GET", "/panic")
+	if w.Code != http.StatusInternalServerError || strings.TrimSpace(w.Body.String()) != `{"error":"internal"}` {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if reported != "boom" || len(reportedStack) == 0 || len(reportedStack) > 256 {
+		t.Fatalf("unexpected report %v with %d bytes stack", reported, len(reportedStack))
+	}
+	broken := &headerRecorder{ResponseRecorder: httptest.NewRecorder()}
+	(&Engine{s}).ServeHTTP(broken, httptest.NewRequest("GET", "/broken", nil))
+	if broken.headerWritten || broken.Body.Len() != 0 || status != StatusClientClosedRequest {
+		t.Fatalf("expected nothing written to the broken connection, got headers %v body %q status %d",
+			broken.headerWritten, broken.Body.String(), status)
+	}
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Fatalf("expected http.ErrAbortHandler to be panicked again, got %v", err)
+		}
+	}()
+	(&Engine{s}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// RequestIDConfig defines the config of RequestIDWithConfig
+type RequestIDConfig struct {
+	// Header carries the request ID in both the request and the response, default is "X-Request-ID"
+	Header string
+	// Generator generates the ID if the request does not carry a valid one, default is NewUUIDv7
+	Generator func() string
+}
+
+// NewUUIDv7 generates a time-ordered UUID version 7
+func NewUUIDv7() string {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		panic(err)
+	}
+	ms := uint64(time.Now().UnixMilli())
+	binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+	b[6] = b[6]&0x0f | 0x70 // version 7
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+	var dst [36]byte
+	hex.Encode(dst[0:8], b[0:4])
+	dst[8] = '-'
+	hex.Encode(dst[9:13], b[4:6])
+	dst[13] = '-'
+	hex.Encode(dst[14:18], b[6:8])
+	dst[18] = '-'
+	hex.Encode(dst[19:23], b[8:10])
+	dst[23] = '-'
+	hex.Encode(dst[24:], b[10:])
+	return string(dst[:])
+}
+
+// validRequestID accepts the printable IDs up to 128 bytes, so that they are safe to be logged and echoed
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID returns the ID of the request, it requires the RequestID middleware
+func (c *Context) RequestID() string {
+	return c.requestID
+}
+
+// RequestID is a middleware which propagates the "X-Request-ID" header or generates a UUIDv7
+func RequestID() Handler {
+	return RequestIDWithConfig(RequestIDConfig{})
+}
+
+// RequestIDWithConfig is a middleware which reads the request ID from the header or generates one,
+// then stores it in the context and echoes it in the response
+func RequestIDWithConfig(config RequestIDConfig) Handler {
+	if config.Header == "" {
+		config.Header = "X-Request-ID"
+	}
+	if config.Generator == nil {
+		config.Generator = NewUUIDv7
+	}
+	return HandlerFunc(func(c *Context) {
+		id := c.GetHeader(config.Header)
+		if !validRequestID(id) {
+			id = config.Generator()
+		}
+		c.requestID = id
+		c.SetHeader(config.Header, id)
+		c.Next()
+	})
+}
@@ -0,0 +1,28 @@
+package web
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	s := New()
+	s.Use(RequestID())
+	s.GET("/id", HandlerFunc(func(c *Context) { c.String(200, c.RequestID()) }))
+
+	w := serve(s, "GET", "/id")
+	uuidv7 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuidv7.MatchString(w.Body.String()) || w.Header().Get("X-Request-ID") != w.Body.String() {
+		t.Fatalf("unexpected request id %q %v", w.Body.String(), w.Header())
+	}
+	for incoming, keep := range map[string]bool{"abc-123": true, "bad id\n": false} {
+		req := httptest.NewRequest("GET", "/id", nil)
+		req.Header.Set("X-Request-ID", incoming)
+		w = httptest.NewRecorder()
+		(&Engine{s}).ServeHTTP(w, req)
+		if (w.Body.String() == incoming) != keep {
+			t.Fatalf("incoming %q: unexpected request id %q", incoming, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package web
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+)
+
+const noWritten = -1
+
+// StatusClientClosedRequest is recorded when the client closed the connection before the response was sent
+const StatusClientClosedRequest = 499
+
+// errResponseAborted is returned by the writes after the response was aborted
+var errResponseAborted = errors.New("the response was aborted")
+
+// ResponseWriter wraps http.ResponseWriter to track the status, size and write state of the response,
+// it is the source of truth about the response for middlewares
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	// Status returns the status code of the response, it is 200 if not set
+	Status() int
+	// Size returns the bytes written to the response body
+	Size() int
+	// Written reports whether the headers have been sent
+	Written() bool
+	// WriteHeaderNow sends the headers if they have not been sent
+	WriteHeaderNow()
+	// Unwrap returns the origin writer, it is used by http.ResponseController
+	Unwrap() http.ResponseWriter
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	beforeWrite []func() // called in reverse order right before the headers are sent
+	logger      *slog.Logger
+	aborted     bool // nothing is written to the connection any more
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK, size: noWritten, logger: defaultLogger}
+}
+
+// WriteHeader only records the status code, the headers are sent at the first write
+func (w *responseWriter) WriteHeader(code int) {
+	if code <= 0 || w.status == code {
+		return
+	}
+	if w.Written() {
+		w.logger.Warn("Headers were already written", "status", w.status, "wanted", code)
+		return
+	}
+	w.status = code
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if w.Written() {
+		return
+	}
+	hooks := w.beforeWrite
+	w.beforeWrite = nil
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// abort records the status and marks the response as sent without writing anything,
+// it is used when the connection is broken
+func (w *responseWriter) abort(status int) {
+	w.status = status
+	w.beforeWrite = nil
+	w.aborted = true
+	if w.size < 0 {
+		w.size = 0
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if w.aborted {
+		return 0, errResponseAborted
+	}
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return max(w.size, 0)
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+func (w *responseWriter) Flush() {
+	if w.aborted {
+		return
+	}
+	w.WriteHeaderNow()
+	if err := http.NewResponseController(w.ResponseWriter).Flush(); err != nil {
+		w.logger.Warn(err.Error())
+	}
+}
+
+// Hijack marks the response as written only if the connection is taken over,
+// so that the handler can still respond to a failed hijack
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.size < 0 {
+		w.size = 0
+	}
+	return conn, rw, err
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,40 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResponseWriter(t *testing.T) {
+	s := New()
+	var status, size, statusCode int
+	s.Use(HandlerFunc(func(c *Context) {
+		c.Next()
+		status, size, statusCode = c.Writer.Status(), c.Writer.Size(), c.StatusCode
+	}))
+	s.GET("/raw", HandlerFunc(func(c *Context) {
+		c.Writer.WriteHeader(http.StatusCreated)
+		_, _ = c.Writer.Write([]byte("hello"))
+		c.Writer.WriteHeader(http.StatusTeapot) // ignored after the headers were sent
+		c.Writer.Flush()
+		if _, _, err := http.NewResponseController(c.Writer).Hijack(); err == nil {
+			t.Error("expected the recorder not to be hijackable")
+		}
+	}))
+	s.GET("/upgrade", HandlerFunc(func(c *Context) {
+		if _, _, err := c.Writer.Hijack(); err != nil {
+			c.String(http.StatusInternalServerError, "upgrade failed")
+		}
+	}))
+	s.GET("/empty", HandlerFunc(func(c *Context) { c.Status(http.StatusNoContent) }))
+
+	if w := serve(s, "GET", "/raw"); w.Code != http.StatusCreated || status != http.StatusCreated || size != 5 || !w.Flushed {
+		t.Fatalf("unexpected response %d, tracked status %d size %d", w.Code, status, size)
+	}
+	if w := serve(s, "GET", "/upgrade"); w.Code != http.StatusInternalServerError || w.Body.String() != "upgrade failed" {
+		t.Fatalf("expected the handler to respond to the failed hijack, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serve(s, "GET", "/empty"); w.Code != http.StatusNoContent || status != http.StatusNoContent || size != 0 || statusCode != http.StatusNoContent {
+		t.Fatalf("unexpected response %d, tracked status %d size %d", w.Code, status, size)
+	}
+}
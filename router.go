@@ -3,17 +3,53 @@ package web
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// routingTable is an immutable snapshot of all routes and groups,
+// every update works on a copy and publishes it as a whole
+type routingTable struct {
+	tree     map[string]*trieTreeR
+	groups   *trieTreeG
+	versions map[string]*versionSet // version sets by group prefix
+	total    int
+}
+
+func (t *routingTable) clone() *routingTable {
+	tree := make(map[string]*trieTreeR, len(t.tree))
+	for method, tr := range t.tree {
+		tree[method] = tr
+	}
+	return &routingTable{tree: tree, groups: t.groups, versions: t.versions, total: t.total}
+}
+
 type router struct {
-	tree  map[string]*trieTreeR
-	total int
+	mu     sync.Mutex // serializes the updates
+	table  atomic.Pointer[routingTable]
+	server *Server
+}
+
+func newRouter(root *RouterGroup) *router {
+	r := &router{server: root.server}
+	r.table.Store(&routingTable{
+		tree:   make(map[string]*trieTreeR),
+		groups: newTrieTreeG(root),
+	})
+	return r
 }
 
-func newRouter() *router {
-	return &router{
-		tree: make(map[string]*trieTreeR),
+// cleanPath collapses the repeated slashes and resolves "." and "..", so that the group middlewares
+// which are searched by prefix see the same path as the routes which skip the empty parts
+func cleanPath(p string) string {
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
 	}
+	return cleaned
 }
 
 func parsePattern(pattern string) []string {
@@ -39,28 +75,121 @@ func parsePattern(pattern string) []string {
 	return parts
 }
 
+// load returns the routing table which is currently published
+func (r *router) load() *routingTable {
+	return r.table.Load()
+}
+
+// update applies fn to a copy of the current routing table and publishes the copy
+func (r *router) update(fn func(t *routingTable)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.table.Load().clone()
+	fn(t)
+	r.table.Store(t)
+}
+
 func (r *router) addRoute(method string, pattern string, handler Handler) {
 	parts := parsePattern(pattern)
-	if _, has := r.tree[method]; !has {
-		r.tree[method] = newTrieTreeR()
+	for _, part := range parts {
+		if (part[0] == ':' || part[0] == '*') && len(part) == 1 {
+			r.server.logger.Error(fmt.Sprintf("the routing path \"%s\" cannot contain nodes with only \"*\" or \":\"", pattern))
+			os.Exit(1)
+		}
 	}
-	r.total += r.tree[method].insert(parts, handler)
+	name := ""
+	if named, ok := handler.(*namedHandler); ok {
+		name, handler = named.name, named.handler
+	}
+	r.update(func(t *routingTable) {
+		tree, has := t.tree[method]
+		if has {
+			tree = tree.clone()
+		} else {
+			tree = newTrieTreeR()
+		}
+		t.tree[method] = tree
+		added := tree.insert(parts, handler, name)
+		if added == 0 {
+			r.server.logger.Warn("A route coverage occurred", "method", method, "pattern", pattern)
+		}
+		t.total += added
+	})
+}
+
+func (r *router) removeRoute(method string, pattern string) bool {
+	parts := parsePattern(pattern)
+	removed := false
+	r.update(func(t *routingTable) {
+		tree, has := t.tree[method]
+		if !has {
+			return
+		}
+		tree = tree.clone()
+		if removed = tree.remove(parts); removed {
+			t.tree[method] = tree
+			t.total--
+		}
+	})
+	return removed
+}
+
+func (r *router) addGroup(group *RouterGroup) {
+	r.update(func(t *routingTable) {
+		t.groups = t.groups.clone()
+		if t.groups.insert(group.prefix, group) == 0 {
+			r.server.logger.Warn("A group coverage occurred", "prefix", group.prefix)
+		}
+	})
+}
+
+func (r *router) useGroup(group *RouterGroup, middlewares ...Handler) {
+	r.update(func(t *routingTable) {
+		group.middlewares = append(group.middlewares, middlewares...)
+		t.groups = t.groups.clone()
+		t.groups.use(group.prefix, group.middlewares)
+	})
+}
+
+// removeGroup removes the group, its sub groups and all routes under its prefix
+func (r *router) removeGroup(group *RouterGroup) {
+	parts := parsePattern(group.prefix)
+	r.update(func(t *routingTable) {
+		t.groups = t.groups.clone()
+		t.groups.remove(group.prefix)
+		versions := make(map[string]*versionSet, len(t.versions))
+		for prefix, vs := range t.versions {
+			if prefix != group.prefix && !strings.HasPrefix(prefix, group.prefix+"/") {
+				versions[prefix] = vs
+			}
+		}
+		t.versions = versions
+		for method, tree := range t.tree {
+			tree = tree.clone()
+			if n := tree.removeSubtree(parts); n > 0 {
+				t.tree[method] = tree
+				t.total -= n
+			}
+		}
+	})
 }
 
-func (r *router) getRoute(method string, path string) (*nodeR, map[string]string) {
+func (t *routingTable) getRoute(method string, path string) (*nodeR, map[string]string) {
 	searchParts := parsePattern(path)
-	tree, ok := r.tree[method]
+	tree, ok := t.tree[method]
 	if !ok {
 		return nil, nil
 	}
 	return tree.search(searchParts)
 }
 
-func (r *router) handle(c *Context) {
-	n, params := r.getRoute(c.Method, c.Path)
+func (t *routingTable) handle(c *Context) {
+	n, params := t.getRoute(c.Method, c.Path)
 
 	if n != nil {
 		c.params = params
+		c.fullPath = n.pattern
+		c.routeName = n.name
 		c.handlers = append(c.handlers, n.handler)
 	} else {
 		c.handlers = append(c.handlers, HandlerFunc(func(c *Context) {
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func serve(s *Server, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	(&Engine{s}).ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestRemoveRoute(t *testing.T) {
+	s := New()
+	s.GET("/users/:id", HandlerFunc(func(c *Context) { c.String(200, c.Param("id")) }))
+	s.GET("/users/:id/books", HandlerFunc(func(c *Context) { c.String(200, "books") }))
+	if w := serve(s, "GET", "/users/1"); w.Code != 200 || w.Body.String() != "1" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if !s.RemoveRoute("GET", "/users/:id") {
+		t.Fatal("expected the route to be removed")
+	}
+	if s.RemoveRoute("GET", "/users/:id") {
+		t.Fatal("expected the route to be removed only once")
+	}
+	if w := serve(s, "GET", "/users/1"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+	if w := serve(s, "GET", "/users/1/books"); w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if total := s.router.load().total; total != 1 {
+		t.Fatalf("expected 1 route, got %d", total)
+	}
+}
+
+func TestRemoveGroup(t *testing.T) {
+	s := New()
+	used := 0
+	g := s.Group("plugin").Use(HandlerFunc(func(c *Context) { used++; c.Next() }))
+	g.GET("/a", HandlerFunc(func(c *Context) { c.String(200, "a") }))
+	g.Group("sub").GET("/b", HandlerFunc(func(c *Context) { c.String(200, "b") }))
+	s.GET("/pluginx", HandlerFunc(func(c *Context) { c.String(200, "x") }))
+	if w := serve(s, "GET", "/plugin/sub/b"); w.Code != 200 || used != 1 {
+		t.Fatalf("unexpected response %d, middleware used %d", w.Code, used)
+	}
+	g.Remove()
+	for _, path := range []string{"/plugin/a", "/plugin/sub/b"} {
+		if w := serve(s, "GET", path); w.Code != http.StatusNotFound {
+			t.Fatalf("expected 404 at %s, got %d", path, w.Code)
+		}
+	}
+	if w := serve(s, "GET", "/pluginx"); w.Code != 200 || used != 1 {
+		t.Fatalf("unexpected response %d, middleware used %d", w.Code, used)
+	}
+}
+
+func TestConcurrentRouteUpdate(t *testing.T) {
+	s := New()
+	s.GET("/static", HandlerFunc(func(c *Context) { c.String(200, "ok") }))
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			s.GET("/dynamic", HandlerFunc(func(c *Context) { c.String(200, "ok") }))
+			s.Use(HandlerFunc(func(c *Context) { c.Next() }))
+			s.RemoveRoute("GET", "/dynamic")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if w := serve(s, "GET", "/static"); w.Code != 200 {
+				t.Errorf("expected 200, got %d", w.Code)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestFullPathAndRouteName(t *testing.T) {
+	s := New()
+	var fullPath, routeName string
+	s.Use(HandlerFunc(func(c *Context) {
+		c.Next()
+		fullPath, routeName = c.FullPath(), c.RouteName()
+	}))
+	s.GET("/users/:id", Named("user.show", HandlerFunc(func(c *Context) { c.String(200, c.Param("id")) })))
+	s.GET("/files/*path", HandlerFunc(func(c *Context) { c.String(200, "file") }))
+
+	if w := serve(s, "GET", "/users/123"); w.Body.String() != "123" || fullPath != "/users/:id" || routeName != "user.show" {
+		t.Fatalf("unexpected %q %q %q", w.Body.String(), fullPath, routeName)
+	}
+	if serve(s, "GET", "/files/a/b"); fullPath != "/files/*path" || routeName != "" {
+		t.Fatalf("unexpected %q %q", fullPath, routeName)
+	}
+	if serve(s, "GET", "/missing"); fullPath != "" {
+		t.Fatalf("unexpected full path %q of unmatched request", fullPath)
+	}
+}
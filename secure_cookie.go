@@ -0,0 +1,171 @@
+package web
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCookieNoKeys   = errors.New("no cookie keys configured on the server")
+	ErrCookieTampered = errors.New("cookie value has been tampered with")
+	ErrCookieExpired  = errors.New("cookie value has expired")
+)
+
+// SetCookieKeys sets the HMAC keys of signed cookies, the first key signs and all keys verify
+func (server *Server) SetCookieKeys(keys ...[]byte) {
+	server.cookieKeys = keys
+}
+
+// SetCookieCipherKeys sets the AES keys of encrypted cookies, the first key encrypts and all keys decrypt.
+// Each key must be 16, 24 or 32 bytes to select AES-128, AES-192 or AES-256.
+func (server *Server) SetCookieCipherKeys(keys ...[]byte) {
+	aeads := make([]cipher.AEAD, 0, len(keys))
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			panic(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(err)
+		}
+		aeads = append(aeads, aead)
+	}
+	server.cookieCiphers = aeads
+}
+
+// cookieExpiry returns the unix time when the cookie expires, 0 means never
+func cookieExpiry(cookie *Cookie) int64 {
+	if cookie.MaxAge > 0 {
+		return time.Now().Unix() + int64(cookie.MaxAge)
+	}
+	if !cookie.Expires.IsZero() {
+		return cookie.Expires.Unix()
+	}
+	return 0
+}
+
+func checkExpiry(exp int64) error {
+	if exp != 0 && exp < time.Now().Unix() {
+		return ErrCookieExpired
+	}
+	return nil
+}
+
+// SetSignedCookie sets the cookie whose value is signed by HMAC-SHA256 so that it cannot be modified by the client
+func (c *Context) SetSignedCookie(cookie *Cookie) error {
+	if len(c.server.cookieKeys) == 0 {
+		return ErrCookieNoKeys
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(cookie.Value)) + "." + strconv.FormatInt(cookieExpiry(cookie), 10)
+	signature, err := generateSignature(c.server.cookieKeys[0], []byte(cookie.Name+"="+payload))
+	if err != nil {
+		return err
+	}
+	signed := *cookie
+	signed.Value = payload + "." + signature
+	c.SetCookie(&signed)
+	return nil
+}
+
+// SignedCookie returns the value of the cookie set by SetSignedCookie after verifying it
+func (c *Context) SignedCookie(name string) (string, error) {
+	if len(c.server.cookieKeys) == 0 {
+		return "", ErrCookieNoKeys
+	}
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	idx := strings.LastIndexByte(raw, '.')
+	if idx < 0 {
+		return "", ErrCookieTampered
+	}
+	payload, signature := raw[:idx], raw[idx+1:]
+	verified := false
+	for _, key := range c.server.cookieKeys {
+		confirmSignature, err := generateSignature(key, []byte(name+"="+payload))
+		if err != nil {
+			return "", err
+		}
+		if hmac.Equal([]byte(signature), []byte(confirmSignature)) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return "", ErrCookieTampered
+	}
+	encodedValue, expStr, found := strings.Cut(payload, ".")
+	if !found {
+		return "", ErrCookieTampered
+	}
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil {
+		return "", ErrCookieTampered
+	}
+	if err = checkExpiry(exp); err != nil {
+		return "", err
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encodedValue)
+	if err != nil {
+		return "", ErrCookieTampered
+	}
+	return string(value), nil
+}
+
+// SetEncryptedCookie sets the cookie whose value is encrypted by AES-GCM so that it can be neither read nor modified by the client
+func (c *Context) SetEncryptedCookie(cookie *Cookie) error {
+	if len(c.server.cookieCiphers) == 0 {
+		return ErrCookieNoKeys
+	}
+	aead := c.server.cookieCiphers[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+8+len(cookie.Value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("nonce generation error: %w", err)
+	}
+	plaintext := binary.BigEndian.AppendUint64(nil, uint64(cookieExpiry(cookie)))
+	plaintext = append(plaintext, cookie.Value...)
+	encrypted := *cookie
+	encrypted.Value = base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, []byte(cookie.Name)))
+	c.SetCookie(&encrypted)
+	return nil
+}
+
+// EncryptedCookie returns the value of the cookie set by SetEncryptedCookie after decrypting it
+func (c *Context) EncryptedCookie(name string) (string, error) {
+	if len(c.server.cookieCiphers) == 0 {
+		return "", ErrCookieNoKeys
+	}
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return "", ErrCookieTampered
+	}
+	for _, aead := range c.server.cookieCiphers {
+		if len(data) < aead.NonceSize() {
+			continue
+		}
+		plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(name))
+		if err != nil || len(plaintext) < 8 {
+			continue
+		}
+		if err = checkExpiry(int64(binary.BigEndian.Uint64(plaintext))); err != nil {
+			return "", err
+		}
+		return string(plaintext[8:]), nil
+	}
+	return "", ErrCookieTampered
+}
@@ -0,0 +1,64 @@
+package web
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecureCookie(t *testing.T) {
+	s := New()
+	s.SetCookieKeys([]byte("new-key"), []byte("old-key"))
+	s.SetCookieCipherKeys([]byte("0123456789abcdef"))
+	s.GET("/set", HandlerFunc(func(c *Context) {
+		_ = c.SetSignedCookie(&Cookie{Name: "signed", Value: "dark mode"})
+		_ = c.SetEncryptedCookie(&Cookie{Name: "secret", Value: "lang=zh"})
+		_ = c.SetSignedCookie(&Cookie{Name: "expired", Value: "x", Expires: time.Now().Add(-time.Minute)})
+	}))
+	s.GET("/get", HandlerFunc(func(c *Context) {
+		signed, err1 := c.SignedCookie("signed")
+		secret, err2 := c.EncryptedCookie("secret")
+		_, err3 := c.SignedCookie("expired")
+		c.JSON(200, H{"signed": signed, "secret": secret, "errs": []any{err1, err2, err3 == ErrCookieExpired}})
+	}))
+	s.GET("/tampered", HandlerFunc(func(c *Context) {
+		_, err1 := c.SignedCookie("signed")
+		_, err2 := c.EncryptedCookie("secret")
+		if err1 != ErrCookieTampered || err2 != ErrCookieTampered {
+			t.Errorf("expected tampered errors, got %v and %v", err1, err2)
+		}
+	}))
+
+	set := serve(s, "GET", "/set")
+	req := httptest.NewRequest("GET", "/get", nil)
+	for _, cookie := range set.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	(&Engine{s}).ServeHTTP(w, req)
+	if body := strings.TrimSpace(w.Body.String()); body != `{"errs":[null,null,true],"secret":"lang=zh","signed":"dark mode"}` {
+		t.Fatalf("unexpected body %s", body)
+	}
+
+	// the signature made by a rotated key is still verified
+	s.SetCookieKeys([]byte("newer-key"), []byte("new-key"))
+	w = httptest.NewRecorder()
+	(&Engine{s}).ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), `"signed":"dark mode"`) {
+		t.Fatalf("unexpected body %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/tampered", nil)
+	for _, cookie := range set.Result().Cookies() {
+		b := []byte(cookie.Value)
+		if b[1] == 'A' {
+			b[1] = 'B'
+		} else {
+			b[1] = 'A'
+		}
+		cookie.Value = string(b)
+		req.AddCookie(cookie)
+	}
+	(&Engine{s}).ServeHTTP(httptest.NewRecorder(), req)
+}
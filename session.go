@@ -0,0 +1,382 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionRecord is the data of a session which is kept by a SessionStore
+type SessionRecord struct {
+	ID        string         `json:"id"`
+	Values    map[string]any `json:"values,omitempty"`
+	Flashes   map[string]any `json:"flashes,omitempty"`
+	CreatedAt int64          `json:"created"`  // unix time, used for the absolute timeout
+	LastSeen  int64          `json:"lastSeen"` // unix time, used for the idle timeout
+}
+
+func (r *SessionRecord) clone() *SessionRecord {
+	cp := *r
+	cp.Values = make(map[string]any, len(r.Values))
+	for k, v := range r.Values {
+		cp.Values[k] = v
+	}
+	cp.Flashes = make(map[string]any, len(r.Flashes))
+	for k, v := range r.Flashes {
+		cp.Flashes[k] = v
+	}
+	return &cp
+}
+
+// SessionStore defines the storage of sessions, implement it to use a shared store such as Redis
+type SessionStore interface {
+	// Load returns the record of the token which is read from the session cookie, nil means not found
+	Load(token string) (*SessionRecord, error)
+	// Save keeps the record for ttl and returns the token which is written into the session cookie
+	Save(record *SessionRecord, ttl time.Duration) (string, error)
+	// Delete removes the record of the token
+	Delete(token string) error
+}
+
+// MemorySessionStore keeps the sessions in process memory, expired sessions are evicted lazily
+type MemorySessionStore struct {
+	mu        sync.Mutex
+	records   map[string]memorySession
+	lastSweep time.Time
+}
+
+type memorySession struct {
+	record   *SessionRecord
+	expireAt time.Time
+}
+
+// NewMemorySessionStore is the constructor of MemorySessionStore
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{records: make(map[string]memorySession), lastSweep: time.Now()}
+}
+
+func (s *MemorySessionStore) Load(token string) (*SessionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ms, has := s.records[token]
+	if !has {
+		return nil, nil
+	}
+	if time.Now().After(ms.expireAt) {
+		delete(s.records, token)
+		return nil, nil
+	}
+	return ms.record.clone(), nil
+}
+
+func (s *MemorySessionStore) Save(record *SessionRecord, ttl time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	// sweep the expired sessions at most once per minute
+	if now.Sub(s.lastSweep) > time.Minute {
+		for token, ms := range s.records {
+			if now.After(ms.expireAt) {
+				delete(s.records, token)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.records[record.ID] = memorySession{record.clone(), now.Add(ttl)}
+	return record.ID, nil
+}
+
+func (s *MemorySessionStore) Delete(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, token)
+	return nil
+}
+
+// CookieSessionStore keeps the whole session in the signed cookie, the first key signs and all keys verify.
+// The values are encoded by JSON, so they are read back as the types produced by encoding/json.
+type CookieSessionStore struct {
+	keys [][]byte
+}
+
+// NewCookieSessionStore is the constructor of CookieSessionStore
+func NewCookieSessionStore(keys ...[]byte) *CookieSessionStore {
+	if len(keys) == 0 {
+		panic("the cookie session store needs at least one key")
+	}
+	return &CookieSessionStore{keys: keys}
+}
+
+type cookieSession struct {
+	*SessionRecord
+	ExpireAt int64 `json:"exp"`
+}
+
+func (s *CookieSessionStore) Load(token string) (*SessionRecord, error) {
+	idx := strings.LastIndexByte(token, '.')
+	if idx < 0 {
+		return nil, nil
+	}
+	payload, signature := token[:idx], token[idx+1:]
+	verified := false
+	for _, key := range s.keys {
+		confirmSignature, err := generateSignature(key, []byte(payload))
+		if err != nil {
+			return nil, err
+		}
+		if hmac.Equal([]byte(signature), []byte(confirmSignature)) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, nil
+	}
+	cs := &cookieSession{}
+	if err = json.Unmarshal(data, cs); err != nil || cs.SessionRecord == nil || cs.ExpireAt < time.Now().Unix() {
+		return nil, nil
+	}
+	return cs.SessionRecord, nil
+}
+
+func (s *CookieSessionStore) Save(record *SessionRecord, ttl time.Duration) (string, error) {
+	data, err := json.Marshal(&cookieSession{record, time.Now().Add(ttl).Unix()})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	signature, err := generateSignature(s.keys[0], []byte(payload))
+	if err != nil {
+		return "", err
+	}
+	token := payload + "." + signature
+	if len(token) > 4000 {
+		return "", fmt.Errorf("session is too large to be stored in a cookie")
+	}
+	return token, nil
+}
+
+func (s *CookieSessionStore) Delete(string) error {
+	return nil
+}
+
+// SessionConfig defines the config of Sessions
+type SessionConfig struct {
+	Store           SessionStore  // default is a MemorySessionStore
+	CookieName      string        // default is "session"
+	IdleTimeout     time.Duration // the session expires if not used for this duration, default is 30 minutes
+	AbsoluteTimeout time.Duration // the session expires after this duration since created, default is 24 hours
+	// Cookie defines the attributes of the session cookie, Name, Value, MaxAge and Expires are ignored,
+	// and the session cookie is always HttpOnly
+	Cookie Cookie
+}
+
+// Session is the session of the current request, it is returned by Context.Session.
+// The session is saved right before the response headers are sent, so change it before writing the body,
+// the changes after that such as c.JSON(...) then Set are lost and logged as warnings.
+type Session struct {
+	mu         sync.Mutex // a late handler of Timeout may change the session while it is being saved
+	record     *SessionRecord
+	token      string // the token read from the request
+	isNew      bool
+	changed    bool
+	regenerate bool
+	destroyed  bool
+	saved      bool         // whether the response headers were sent
+	logger     *slog.Logger // it warns the changes after saved
+}
+
+func newSessionID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func newSession() *Session {
+	now := time.Now().Unix()
+	return &Session{
+		record: &SessionRecord{ID: newSessionID(), Values: make(map[string]any), Flashes: make(map[string]any), CreatedAt: now, LastSeen: now},
+		isNew:  true,
+	}
+}
+
+// change marks the session as changed and warns if it was saved already, it is called with the lock held
+func (s *Session) change() {
+	s.changed = true
+	if s.saved {
+		s.logger.Warn("The session changed after the response headers were sent, the change is lost")
+	}
+}
+
+// ID returns the session ID
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record.ID
+}
+
+// Get returns the value of the key
+func (s *Session) Get(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, _ := s.record.Values[key]
+	return value
+}
+
+// Set sets the value of the key
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record.Values[key] = value
+	s.change()
+}
+
+// Delete removes the value of the key
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.record.Values, key)
+	s.change()
+}
+
+// Flash sets a value which can only be read once by GetFlash, usually in the next request
+func (s *Session) Flash(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record.Flashes[key] = value
+	s.change()
+}
+
+// GetFlash returns the flash value of the key and removes it
+func (s *Session) GetFlash(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, has := s.record.Flashes[key]
+	if has {
+		delete(s.record.Flashes, key)
+		s.change()
+	}
+	return value
+}
+
+// Regenerate changes the session ID and keeps the values, call it on login to prevent session fixation
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record.ID = newSessionID()
+	s.record.CreatedAt = time.Now().Unix()
+	s.regenerate = true
+	s.change()
+}
+
+// Destroy removes the session from the store and the client
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.destroyed = true
+	s.change()
+}
+
+// Session returns the session of the request, it requires the Sessions middleware
+func (c *Context) Session() *Session {
+	session, _ := c.Extra("session").(*Session)
+	return session
+}
+
+// Sessions is a middleware which provides the server-side sessions by Context.Session
+func Sessions(config SessionConfig) Handler {
+	if config.Store == nil {
+		config.Store = NewMemorySessionStore()
+	}
+	if config.CookieName == "" {
+		config.CookieName = "session"
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 30 * time.Minute
+	}
+	if config.AbsoluteTimeout <= 0 {
+		config.AbsoluteTimeout = 24 * time.Hour
+	}
+	config.Cookie.HttpOnly = true
+	load := func(c *Context) *Session {
+		token, err := c.Cookie(config.CookieName)
+		if err != nil || token == "" {
+			return newSession()
+		}
+		record, err := config.Store.Load(token)
+		if err != nil {
+			c.Logger().Error("session load error", "error", err.Error())
+		}
+		now := time.Now()
+		if record == nil ||
+			now.Sub(time.Unix(record.LastSeen, 0)) > config.IdleTimeout ||
+			now.Sub(time.Unix(record.CreatedAt, 0)) > config.AbsoluteTimeout {
+			session := newSession()
+			session.token = token
+			session.regenerate = record != nil // drop the expired record
+			return session
+		}
+		if record.Values == nil {
+			record.Values = make(map[string]any)
+		}
+		if record.Flashes == nil {
+			record.Flashes = make(map[string]any)
+		}
+		return &Session{record: record, token: token}
+	}
+	save := func(c *Context, session *Session) {
+		cookie := config.Cookie
+		cookie.Name = config.CookieName
+		if session.destroyed {
+			if session.token != "" {
+				if err := config.Store.Delete(session.token); err != nil {
+					c.Logger().Error("session delete error", "error", err.Error())
+				}
+				cookie.MaxAge = -1
+				c.SetCookie(&cookie)
+			}
+			return
+		}
+		if session.isNew && !session.changed {
+			return
+		}
+		if session.regenerate && session.token != "" {
+			if err := config.Store.Delete(session.token); err != nil {
+				c.Logger().Error("session delete error", "error", err.Error())
+			}
+		}
+		now := time.Now()
+		session.record.LastSeen = now.Unix()
+		ttl := min(config.IdleTimeout, time.Unix(session.record.CreatedAt, 0).Add(config.AbsoluteTimeout).Sub(now))
+		token, err := config.Store.Save(session.record, ttl)
+		if err != nil {
+			c.Logger().Error("session save error", "error", err.Error())
+			return
+		}
+		cookie.Value = token
+		c.SetCookie(&cookie)
+	}
+	return HandlerFunc(func(c *Context) {
+		session := load(c)
+		c.SetExtra("session", session)
+		c.BeforeWriteHeader(func() {
+			session.mu.Lock()
+			defer session.mu.Unlock()
+			save(c, session)
+			session.saved, session.logger = true, c.Logger()
+		})
+		c.Next()
+	})
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveWithCookies(s *Server, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	(&Engine{s}).ServeHTTP(w, req)
+	return w
+}
+
+func TestSessions(t *testing.T) {
+	for name, store := range map[string]SessionStore{
+		"memory": NewMemorySessionStore(),
+		"cookie": NewCookieSessionStore([]byte("key")),
+	} {
+		s := New()
+		s.Use(Sessions(SessionConfig{Store: store}))
+		s.GET("/login", HandlerFunc(func(c *Context) {
+			c.Session().Regenerate()
+			c.Session().Set("user", "admin")
+			c.Session().Flash("msg", "welcome")
+		}))
+		s.GET("/me", HandlerFunc(func(c *Context) {
+			c.String(200, "%v %v", c.Session().Get("user"), c.Session().GetFlash("msg"))
+		}))
+		s.GET("/logout", HandlerFunc(func(c *Context) { c.Session().Destroy() }))
+
+		if w := serve(s, "GET", "/me"); w.Body.String() != "<nil> <nil>" || len(w.Result().Cookies()) != 0 {
+			t.Fatalf("%s: unexpected anonymous response %q %v", name, w.Body.String(), w.Result().Cookies())
+		}
+		cookies := serve(s, "GET", "/login").Result().Cookies()
+		w := serveWithCookies(s, "/me", cookies)
+		if w.Body.String() != "admin welcome" {
+			t.Fatalf("%s: unexpected body %q", name, w.Body.String())
+		}
+		cookies = w.Result().Cookies()
+		if w = serveWithCookies(s, "/me", cookies); w.Body.String() != "admin <nil>" {
+			t.Fatalf("%s: expected the flash to be read once, got %q", name, w.Body.String())
+		}
+		w = serveWithCookies(s, "/logout", cookies)
+		if c := w.Result().Cookies(); len(c) != 1 || c[0].MaxAge != -1 {
+			t.Fatalf("%s: expected the session cookie to be deleted, got %v", name, c)
+		}
+		if name == "memory" {
+			if w = serveWithCookies(s, "/me", cookies); w.Body.String() != "<nil> <nil>" {
+				t.Fatalf("%s: expected the session to be destroyed, got %q", name, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestSessionChangedAfterSaved(t *testing.T) {
+	var buf bytes.Buffer
+	s := New()
+	s.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	s.Use(Sessions(SessionConfig{}))
+	s.GET("/late", HandlerFunc(func(c *Context) {
+		c.String(200, "ok")
+		c.Session().Set("user", "admin")
+	}))
+	if w := serve(s, "GET", "/late"); len(w.Result().Cookies()) != 0 {
+		t.Fatalf("expected no session cookie, got %v", w.Result().Cookies())
+	}
+	if !strings.Contains(buf.String(), "The session changed after the response headers were sent") {
+		t.Fatalf("expected a warning, got %q", buf.String())
+	}
+}
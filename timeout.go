@@ -0,0 +1,180 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// TimeoutConfig defines the config of TimeoutWithConfig
+type TimeoutConfig struct {
+	Timeout time.Duration
+	// Handler responds when the handler does not finish in time, default is a 503 response
+	Handler func(c *Context)
+}
+
+// timeoutWriter buffers the response of the handler, the writes after timeout are discarded
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	status   int
+	buf      bytes.Buffer
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.buf.Write(data)
+}
+
+// fork copies the context for the handlers which run in another goroutine,
+// the writer, the request and the extras are replaced so that nothing is shared but the read-only fields
+func (c *Context) fork(w http.ResponseWriter, req *http.Request) *Context {
+	cc := *c
+	writer := newResponseWriter(w)
+	writer.logger = c.writer.logger
+	cc.Writer, cc.writer, cc.Request = writer, writer, req
+	cc.extras = make(map[string]any, len(c.extras))
+	for k, v := range c.extras {
+		cc.extras[k] = v
+	}
+	cc.inTimeout = true
+	return &cc
+}
+
+// runWithTimeout runs fn with a forked context whose request has the deadline,
+// the response is copied back if fn finishes in time, otherwise onTimeout responds
+func runWithTimeout(c *Context, timeout time.Duration, onTimeout func(c *Context), fn func(cc *Context)) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	tw := &timeoutWriter{header: c.Writer.Header().Clone(), status: http.StatusOK}
+	cc := c.fork(tw, c.Request.WithContext(ctx))
+	done := make(chan struct{})
+	panicChan := make(chan any, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					panicChan <- err
+					return
+				}
+				// skip runtime.Callers, callers and this function
+				panicChan <- &forkedPanic{value: err, stack: debug.Stack(), pcs: callers(3)}
+				return
+			}
+			close(done)
+		}()
+		fn(cc)
+	}()
+
+	select {
+	case err := <-panicChan:
+		panic(err)
+	case <-done:
+		if cc.writer.aborted {
+			c.writer.abort(cc.writer.status)
+			c.index = cc.index
+			return
+		}
+		cc.Writer.WriteHeaderNow()
+		header := c.Writer.Header()
+		for k := range header {
+			delete(header, k)
+		}
+		for k, v := range tw.header {
+			header[k] = v
+		}
+		for k, v := range cc.extras {
+			c.extras[k] = v
+		}
+		c.index = cc.index
+		c.Status(tw.status)
+		if tw.buf.Len() > 0 {
+			if _, err := c.Writer.Write(tw.buf.Bytes()); err != nil {
+				panic(err)
+			}
+		}
+	case <-ctx.Done():
+		tw.mu.Lock()
+		tw.timedOut = true
+		tw.mu.Unlock()
+		c.Abort()
+		onTimeout(c)
+	}
+}
+
+func defaultTimeoutHandler(c *Context) {
+	c.Fail(http.StatusServiceUnavailable, "request timeout")
+}
+
+// Timeout is a middleware which responds 503 if the handlers do not finish in time
+func Timeout(d time.Duration) Handler {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: d})
+}
+
+// TimeoutWithConfig is a middleware which installs a deadline on the request context.
+// The response is buffered, and if the handlers do not finish in time, config.Handler responds
+// and the late writes are discarded. The timeout of the route wrapped by WithTimeout takes precedence.
+// The late handlers still share the values of the extras, the session and the span are safe to use,
+// but the other values which are changed by the handlers must be safe for concurrent use.
+func TimeoutWithConfig(config TimeoutConfig) Handler {
+	if config.Timeout <= 0 {
+		panic("the timeout must > 0")
+	}
+	if config.Handler == nil {
+		config.Handler = defaultTimeoutHandler
+	}
+	return HandlerFunc(func(c *Context) {
+		if c.inTimeout {
+			c.Next()
+			return
+		}
+		timeout := config.Timeout
+		if th, ok := c.handlers[len(c.handlers)-1].(*timeoutHandler); ok {
+			timeout = th.timeout
+		}
+		runWithTimeout(c, timeout, config.Handler, func(cc *Context) {
+			cc.Next()
+		})
+	})
+}
+
+type timeoutHandler struct {
+	timeout time.Duration
+	handler Handler
+}
+
+func (h *timeoutHandler) Handle(c *Context) {
+	if c.inTimeout {
+		h.handler.Handle(c)
+		return
+	}
+	runWithTimeout(c, h.timeout, defaultTimeoutHandler, h.handler.Handle)
+}
+
+// WithTimeout wraps the route handler to respond 503 if it does not finish in time, it overrides the timeout of Timeout
+func WithTimeout(d time.Duration, handler Handler) Handler {
+	if d <= 0 {
+		panic("the timeout must > 0")
+	}
+	return &timeoutHandler{d, handler}
+}
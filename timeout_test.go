@@ -0,0 +1,89 @@
+package web
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	s := New()
+	s.Use(TimeoutWithConfig(TimeoutConfig{Timeout: 20 * time.Millisecond, Handler: func(c *Context) {
+		c.Fail(http.StatusGatewayTimeout, "too slow")
+	}}))
+	slow := HandlerFunc(func(c *Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(50 * time.Millisecond):
+		}
+		c.SetHeader("X-Late", "1")
+		c.String(200, "late")
+	})
+	s.GET("/slow", slow)
+	s.GET("/override", WithTimeout(time.Second, slow))
+	s.GET("/fast", HandlerFunc(func(c *Context) {
+		c.SetExtra("k", "v")
+		c.SetHeader("X-Fast", "1")
+		c.String(http.StatusCreated, "fast")
+	}))
+
+	if w := serve(s, "GET", "/slow"); w.Code != http.StatusGatewayTimeout || w.Body.String() != "too slow" || w.Header().Get("X-Late") != "" {
+		t.Fatalf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if w := serve(s, "GET", "/override"); w.Code != 200 || w.Body.String() != "late" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if w := serve(s, "GET", "/fast"); w.Code != http.StatusCreated || w.Body.String() != "fast" || w.Header().Get("X-Fast") != "1" {
+		t.Fatalf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	time.Sleep(60 * time.Millisecond) // let the late handler write after timeout
+}
+
+func panicUnderTimeout(c *Context) {
+	panic("boom")
+}
+
+func TestTimeoutPanicStack(t *testing.T) {
+	s := New()
+	var reported any
+	var reportedStack string
+	s.Use(RecoveryWithConfig(RecoveryConfig{Reporter: func(c *Context, err any, stack []byte) {
+		reported, reportedStack = err, string(stack)
+	}}), Timeout(time.Second))
+	s.GET("/panic", HandlerFunc(func(c *Context) {
+		c.SetExtra("k", "v")
+		panicUnderTimeout(c)
+	}))
+	if w := serve(s, "GET", "/panic"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	if reported != "boom" || !strings.Contains(reportedStack, "panicUnderTimeout") {
+		t.Fatalf("expected the stack of the handler, got %v\n%s", reported, reportedStack)
+	}
+}
+
+func TestTimeoutLateHandlerSharedState(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	s := New()
+	s.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil))) // the lost session changes are warned
+	s.Use(Sessions(SessionConfig{}), Tracing(exporter), Timeout(10*time.Millisecond))
+	done := make(chan struct{})
+	s.GET("/slow", HandlerFunc(func(c *Context) {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			c.Session().Set("i", i)
+			c.Span().SetAttribute("i", i)
+			time.Sleep(time.Millisecond)
+		}
+	}))
+	if w := serve(s, "GET", "/slow"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	<-done
+	if spans := exporter.Spans(); len(spans) != 1 || spans[0].Attributes["http.response.status_code"] != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+}
@@ -0,0 +1,410 @@
+package web
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanContext is the W3C trace context of a span
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	Flags      byte   // bit 0 is the sampled flag
+	TraceState string // the vendor specific "tracestate" which is propagated as is
+}
+
+// IsValid reports whether both the trace ID and the span ID are non-zero
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// IsSampled reports whether the sampled flag is set
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&0x01 != 0
+}
+
+// Traceparent formats the span context as the "traceparent" header
+func (sc SpanContext) Traceparent() string {
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+// ParseTraceparent parses the "traceparent" header, the fields after flags are ignored for the future versions
+func ParseTraceparent(s string) (SpanContext, bool) {
+	var sc SpanContext
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' || (len(s) > 55 && (s[0:2] == "00" || s[55] != '-')) {
+		return sc, false
+	}
+	var version [1]byte
+	var flags [1]byte
+	if !decodeLowerHex(version[:], s[0:2]) || version[0] == 0xff ||
+		!decodeLowerHex(sc.TraceID[:], s[3:35]) || !decodeLowerHex(sc.SpanID[:], s[36:52]) || !decodeLowerHex(flags[:], s[53:55]) {
+		return sc, false
+	}
+	sc.Flags = flags[0]
+	return sc, sc.IsValid()
+}
+
+// decodeLowerHex decodes src into dst, the upper case is rejected as the specification requires
+func decodeLowerHex(dst []byte, src string) bool {
+	if strings.ToLower(src) != src {
+		return false
+	}
+	n, err := hex.Decode(dst, []byte(src))
+	return err == nil && n == len(dst)
+}
+
+// SpanStatus is the status code of a span
+type SpanStatus int
+
+const (
+	SpanStatusUnset SpanStatus = iota
+	SpanStatusOK
+	SpanStatusError
+)
+
+// SpanEvent is an event which happened during a span
+type SpanEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]any
+}
+
+// Span is the server span of a request, it is not changed any more once ended,
+// so that the late handlers of Timeout cannot race with the exporter
+type Span struct {
+	Name          string
+	Context       SpanContext
+	Parent        SpanContext // invalid if the span is a root span
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]any
+	Events        []SpanEvent
+	Status        SpanStatus
+	StatusMessage string
+	mu            sync.Mutex
+	ended         bool
+}
+
+// SetAttribute sets the attribute of the span
+func (s *Span) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.Attributes[key] = value
+	}
+}
+
+// AddEvent adds the event at now to the span
+func (s *Span) AddEvent(name string, attributes map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.Events = append(s.Events, SpanEvent{Name: name, Time: time.Now(), Attributes: attributes})
+	}
+}
+
+// RecordError adds an "exception" event and marks the span as failed
+func (s *Span) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recordError(err)
+}
+
+func (s *Span) recordError(err error) {
+	if s.ended {
+		return
+	}
+	s.Events = append(s.Events, SpanEvent{Name: "exception", Time: time.Now(), Attributes: map[string]any{
+		"exception.type": fmt.Sprintf("%T", err), "exception.message": err.Error(),
+	}})
+	s.Status, s.StatusMessage = SpanStatusError, err.Error()
+}
+
+// SetStatus sets the status of the span
+func (s *Span) SetStatus(status SpanStatus, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.Status, s.StatusMessage = status, message
+	}
+}
+
+// end records the response status and the panic, then ends the span
+func (s *Span) end(status int, panicked any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if panicked != nil {
+		status = http.StatusInternalServerError
+		s.recordError(fmt.Errorf("panic: %v", panicked))
+	} else if status >= 500 && s.Status == SpanStatusUnset {
+		s.Status, s.StatusMessage = SpanStatusError, http.StatusText(status)
+	}
+	s.Attributes["http.response.status_code"] = status
+	s.End = time.Now()
+	s.ended = true
+}
+
+// Inject sets the "traceparent" and "tracestate" of the span into the header of an outgoing request
+func (s *Span) Inject(header http.Header) {
+	header.Set("traceparent", s.Context.Traceparent())
+	if s.Context.TraceState != "" {
+		header.Set("tracestate", s.Context.TraceState)
+	} else {
+		header.Del("tracestate")
+	}
+}
+
+// Span returns the server span of the request, it requires the Tracing middleware
+func (c *Context) Span() *Span {
+	return c.span
+}
+
+// SpanExporter exports the ended spans
+type SpanExporter interface {
+	Export(span *Span) error
+}
+
+// InMemoryExporter keeps the exported spans in memory, it is useful in tests
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// NewInMemoryExporter is the constructor of InMemoryExporter
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// Export stores the span
+func (e *InMemoryExporter) Export(span *Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+// Spans returns the exported spans in order
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Span(nil), e.spans...)
+}
+
+// Reset drops the exported spans
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// OTLPExporter sends the spans in batches to an OTLP/HTTP endpoint with the JSON encoding
+type OTLPExporter struct {
+	Endpoint    string       // such as "http://localhost:4318/v1/traces"
+	ServiceName string       // the "service.name" of the resource
+	Client      *http.Client // default is a client with 10s timeout
+	BatchSize   int          // the spans are sent once the batch is full, default is 512
+	mu          sync.Mutex
+	pending     []*Span
+	done        chan struct{}
+	once        sync.Once
+}
+
+// NewOTLPExporter is the constructor of OTLPExporter, the pending spans are also sent every interval
+func NewOTLPExporter(endpoint, serviceName string, interval time.Duration) *OTLPExporter {
+	e := &OTLPExporter{
+		Endpoint:    endpoint,
+		ServiceName: serviceName,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		BatchSize:   512,
+		done:        make(chan struct{}),
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = e.Flush()
+			case <-e.done:
+				return
+			}
+		}
+	}()
+	return e
+}
+
+// Export queues the span and sends the batch in background once it is full
+func (e *OTLPExporter) Export(span *Span) error {
+	e.mu.Lock()
+	e.pending = append(e.pending, span)
+	full := len(e.pending) >= e.BatchSize
+	e.mu.Unlock()
+	if full {
+		go func() { _ = e.Flush() }()
+	}
+	return nil
+}
+
+// Flush sends the pending spans now
+func (e *OTLPExporter) Flush() error {
+	e.mu.Lock()
+	spans := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(otlpRequest(e.ServiceName, spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.Client.Post(e.Endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp exporter: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Close stops the background sending and flushes the pending spans
+func (e *OTLPExporter) Close() error {
+	e.once.Do(func() { close(e.done) })
+	return e.Flush()
+}
+
+// otlpAttributes converts the attributes to the OTLP KeyValue list
+func otlpAttributes(attributes map[string]any) []H {
+	list := make([]H, 0, len(attributes))
+	for k, v := range attributes {
+		var value H
+		switch v := v.(type) {
+		case string:
+			value = H{"stringValue": v}
+		case bool:
+			value = H{"boolValue": v}
+		case int:
+			value = H{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = H{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = H{"doubleValue": v}
+		default:
+			value = H{"stringValue": fmt.Sprint(v)}
+		}
+		list = append(list, H{"key": k, "value": value})
+	}
+	return list
+}
+
+// otlpRequest builds the ExportTraceServiceRequest in the OTLP JSON encoding
+func otlpRequest(serviceName string, spans []*Span) H {
+	list := make([]H, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		events := make([]H, 0, len(s.Events))
+		for _, event := range s.Events {
+			events = append(events, H{
+				"name":         event.Name,
+				"timeUnixNano": strconv.FormatInt(event.Time.UnixNano(), 10),
+				"attributes":   otlpAttributes(event.Attributes),
+			})
+		}
+		span := H{
+			"traceId":           hex.EncodeToString(s.Context.TraceID[:]),
+			"spanId":            hex.EncodeToString(s.Context.SpanID[:]),
+			"traceState":        s.Context.TraceState,
+			"name":              s.Name,
+			"kind":              2, // SPAN_KIND_SERVER
+			"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.End.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.Attributes),
+			"events":            events,
+			"status":            H{"code": int(s.Status), "message": s.StatusMessage},
+		}
+		if s.Parent.IsValid() {
+			span["parentSpanId"] = hex.EncodeToString(s.Parent.SpanID[:])
+		}
+		s.mu.Unlock()
+		list = append(list, span)
+	}
+	return H{"resourceSpans": []H{{
+		"resource":   H{"attributes": otlpAttributes(map[string]any{"service.name": serviceName})},
+		"scopeSpans": []H{{"scope": H{"name": "github.com/go-needle/web"}, "spans": list}},
+	}}}
+}
+
+// TracingConfig defines the config of TracingWithConfig
+type TracingConfig struct {
+	// Exporter exports the sampled spans, it is required
+	Exporter SpanExporter
+	// Sampler decides whether a root span is sampled, the decision of the parent is always followed,
+	// default samples all requests
+	Sampler func(c *Context) bool
+}
+
+// Tracing is a middleware which creates a server span per request and exports it by the exporter
+func Tracing(exporter SpanExporter) Handler {
+	return TracingWithConfig(TracingConfig{Exporter: exporter})
+}
+
+// TracingWithConfig is a middleware which continues the trace of the "traceparent" and "tracestate" headers
+// or starts a new one, the span is named by the route pattern and records the status and the panic
+func TracingWithConfig(config TracingConfig) Handler {
+	if config.Exporter == nil {
+		panic("the exporter of tracing is required")
+	}
+	if config.Sampler == nil {
+		config.Sampler = func(c *Context) bool { return true }
+	}
+	return HandlerFunc(func(c *Context) {
+		span := &Span{Start: time.Now(), Attributes: make(map[string]any)}
+		if parent, ok := ParseTraceparent(c.GetHeader("traceparent")); ok {
+			span.Parent = parent
+			span.Context = SpanContext{TraceID: parent.TraceID, Flags: parent.Flags, TraceState: c.GetHeader("tracestate")}
+		} else {
+			if _, err := rand.Read(span.Context.TraceID[:]); err != nil {
+				panic(err)
+			}
+			if config.Sampler(c) {
+				span.Context.Flags = 0x01
+			}
+		}
+		if _, err := rand.Read(span.Context.SpanID[:]); err != nil {
+			panic(err)
+		}
+		span.Name = c.Method + " " + c.fullPath
+		if c.fullPath == "" {
+			span.Name = c.Method
+		}
+		span.Attributes["http.request.method"] = c.Method
+		span.Attributes["url.path"] = c.Path
+		if c.fullPath != "" {
+			span.Attributes["http.route"] = c.fullPath
+		}
+		c.span = span
+		defer func() {
+			err := recover()
+			span.end(c.Writer.Status(), err)
+			if span.Context.IsSampled() {
+				if exportErr := config.Exporter.Export(span); exportErr != nil {
+					c.Logger().Error("failed to export span", "error", exportErr)
+				}
+			}
+			if err != nil {
+				panic(err)
+			}
+		}()
+		c.Next()
+	})
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	s := New()
+	s.Use(Tracing(exporter))
+	var outgoing http.Header
+	s.GET("/users/:id", HandlerFunc(func(c *Context) {
+		outgoing = http.Header{}
+		c.Span().Inject(outgoing)
+		c.String(200, "ok")
+	}))
+	s.GET("/fail", HandlerFunc(func(c *Context) {
+		c.Span().RecordError(errors.New("db down"))
+		c.String(503, "unavailable")
+	}))
+
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "congo=t61rcWkgMzE")
+	(&Engine{s}).ServeHTTP(httptest.NewRecorder(), req)
+	serve(s, "GET", "/fail")
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /users/:id" || hex.EncodeToString(span.Context.TraceID[:]) != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		hex.EncodeToString(span.Parent.SpanID[:]) != "00f067aa0ba902b7" || span.Attributes["http.response.status_code"] != 200 {
+		t.Fatalf("unexpected span %+v", span)
+	}
+	if outgoing.Get("traceparent") != span.Context.Traceparent() || outgoing.Get("tracestate") != "congo=t61rcWkgMzE" {
+		t.Fatalf("unexpected propagated headers %v", outgoing)
+	}
+	if span = spans[1]; span.Parent.IsValid() || span.Status != SpanStatusError || span.StatusMessage != "db down" || len(span.Events) != 1 {
+		t.Fatalf("unexpected failed span %+v", span)
+	}
+
+	for _, header := range []string{
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, ok := ParseTraceparent(header); ok {
+			t.Errorf("expected %q to be rejected", header)
+		}
+	}
+	if _, ok := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); !ok {
+		t.Error("expected the future version to be accepted")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var data map[string]any
+		_ = json.Unmarshal(body, &data)
+		received <- data
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL+"/v1/traces", "api", time.Hour)
+	s := New()
+	s.Use(Tracing(exporter))
+	s.GET("/x", HandlerFunc(func(c *Context) { c.String(200, "ok") }))
+	serve(s, "GET", "/x")
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := <-received
+	spans := data["resourceSpans"].([]any)[0].(map[string]any)["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	if span := spans[0].(map[string]any); span["name"] != "GET /x" || span["kind"] != float64(2) || len(span["traceId"].(string)) != 32 {
+		t.Fatalf("unexpected span %v", span)
+	}
+}
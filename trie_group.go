@@ -1,10 +1,9 @@
 package web
 
-import "github.com/go-needle/web/log"
-
 type nodeG struct {
-	handle   *RouterGroup
-	children map[byte]*nodeG
+	handle      *RouterGroup
+	middlewares []Handler // snapshot of the group middlewares
+	children    map[byte]*nodeG
 }
 
 func newNodeG(handle *RouterGroup) *nodeG {
@@ -18,6 +17,14 @@ func (n *nodeG) matchChild(b byte) *nodeG {
 	return nil
 }
 
+func (n *nodeG) clone() *nodeG {
+	cp := &nodeG{handle: n.handle, middlewares: n.middlewares, children: make(map[byte]*nodeG, len(n.children))}
+	for b, child := range n.children {
+		cp.children[b] = child.clone()
+	}
+	return cp
+}
+
 type trieTreeG struct {
 	root                 *nodeG
 	maxMiddleWaresLength int
@@ -27,6 +34,10 @@ func newTrieTreeG(rootHandle *RouterGroup) *trieTreeG {
 	return &trieTreeG{newNodeG(rootHandle), 0}
 }
 
+func (t *trieTreeG) clone() *trieTreeG {
+	return &trieTreeG{t.root.clone(), t.maxMiddleWaresLength}
+}
+
 func (t *trieTreeG) insert(prefix string, routerGroup *RouterGroup) int {
 	cur := t.root
 	middleWaresLength := 0
@@ -37,17 +48,17 @@ func (t *trieTreeG) insert(prefix string, routerGroup *RouterGroup) int {
 			cur.children[prefix[i]] = next
 		}
 		if next.handle != nil {
-			middleWaresLength += len(next.handle.middlewares)
+			middleWaresLength += len(next.middlewares)
 		}
 		cur = next
 	}
 	isAdd := true
 	if cur.handle != nil {
 		isAdd = false
-		log.Warnf("A group coverage occurred in \"/%s\"", prefix)
 	}
 	cur.handle = routerGroup
-	t.maxMiddleWaresLength = max(t.maxMiddleWaresLength, middleWaresLength+len(cur.handle.middlewares)>>1)
+	cur.middlewares = routerGroup.middlewares[:len(routerGroup.middlewares):len(routerGroup.middlewares)]
+	t.maxMiddleWaresLength = max(t.maxMiddleWaresLength, middleWaresLength+len(cur.middlewares)>>1)
 	if isAdd {
 		return 1
 	} else {
@@ -55,11 +66,51 @@ func (t *trieTreeG) insert(prefix string, routerGroup *RouterGroup) int {
 	}
 }
 
+// find returns the node of the prefix or nil
+func (t *trieTreeG) find(prefix string) *nodeG {
+	cur := t.root
+	for i := 0; i < len(prefix) && cur != nil; i++ {
+		cur = cur.matchChild(prefix[i])
+	}
+	return cur
+}
+
+// use replaces the middlewares snapshot of the group at prefix
+func (t *trieTreeG) use(prefix string, middlewares []Handler) {
+	cur := t.find(prefix)
+	if cur == nil || cur.handle == nil {
+		return
+	}
+	cur.middlewares = middlewares[:len(middlewares):len(middlewares)]
+	t.maxMiddleWaresLength = max(t.maxMiddleWaresLength, len(cur.middlewares))
+}
+
+// remove removes the group at prefix and all groups nested under it
+func (t *trieTreeG) remove(prefix string) {
+	cur := t.find(prefix)
+	if cur == nil {
+		return
+	}
+	cur.handle = nil
+	cur.middlewares = nil
+	delete(cur.children, '/')
+	// prune the nodes which no longer lead to a group
+	for len(prefix) > 0 {
+		parent := t.find(prefix[:len(prefix)-1])
+		child := parent.children[prefix[len(prefix)-1]]
+		if child.handle != nil || len(child.children) > 0 {
+			break
+		}
+		delete(parent.children, prefix[len(prefix)-1])
+		prefix = prefix[:len(prefix)-1]
+	}
+}
+
 func (t *trieTreeG) search(prefix string) []Handler {
 	cur := t.root
 	middleWares := make([]Handler, 0, t.maxMiddleWaresLength)
 	if cur.handle != nil {
-		middleWares = append(middleWares, cur.handle.middlewares...)
+		middleWares = append(middleWares, cur.middlewares...)
 	}
 	for i := 0; i < len(prefix); i++ {
 		next := cur.matchChild(prefix[i])
@@ -67,7 +118,7 @@ func (t *trieTreeG) search(prefix string) []Handler {
 			break
 		}
 		if next.handle != nil {
-			middleWares = append(middleWares, next.handle.middlewares...)
+			middleWares = append(middleWares, next.middlewares...)
 		}
 		cur = next
 	}
@@ -1,10 +1,6 @@
 package web
 
-import (
-	"fmt"
-	"github.com/go-needle/web/log"
-	"strings"
-)
+import "strings"
 
 type nodeR struct {
 	handler   Handler
@@ -12,6 +8,8 @@ type nodeR struct {
 	jumpChild *nodeR //  ':'
 	stopChild *nodeR // '*'
 	keys      map[int]string
+	pattern   string // the registered pattern of the route
+	name      string // the name of the route given by Named
 }
 
 func newNodeR() *nodeR {
@@ -31,6 +29,53 @@ func (n *nodeR) matchChild(part string) *nodeR {
 	return nil
 }
 
+// structChild returns the child which is registered by the part
+func (n *nodeR) structChild(part string) *nodeR {
+	switch part[0] {
+	case '*':
+		return n.stopChild
+	case ':':
+		return n.jumpChild
+	default:
+		return n.children[part]
+	}
+}
+
+// detachChild removes the child which is registered by the part
+func (n *nodeR) detachChild(part string) {
+	switch part[0] {
+	case '*':
+		n.stopChild = nil
+	case ':':
+		n.jumpChild = nil
+	default:
+		delete(n.children, part)
+	}
+}
+
+func (n *nodeR) isEmpty() bool {
+	return n.handler == nil && len(n.children) == 0 && n.jumpChild == nil && n.stopChild == nil
+}
+
+func (n *nodeR) clone() *nodeR {
+	if n == nil {
+		return nil
+	}
+	cp := &nodeR{
+		handler:   n.handler,
+		children:  make(map[string]*nodeR, len(n.children)),
+		jumpChild: n.jumpChild.clone(),
+		stopChild: n.stopChild.clone(),
+		keys:      n.keys,
+		pattern:   n.pattern,
+		name:      n.name,
+	}
+	for part, child := range n.children {
+		cp.children[part] = child.clone()
+	}
+	return cp
+}
+
 type trieTreeR struct {
 	root              *nodeR
 	heightNodeCount   map[int]int
@@ -41,16 +86,13 @@ func newTrieTreeR() *trieTreeR {
 	return &trieTreeR{newNodeR(), make(map[int]int), 1}
 }
 
-func (t *trieTreeR) insert(parts []string, handler Handler) int {
+func (t *trieTreeR) insert(parts []string, handler Handler, name string) int {
 	cur := t.root
 	keys := make(map[int]string)
 	height := 0
 	for i, part := range parts {
 		next := cur.matchChild(part)
 		height++
-		if (part[0] == ':' || part[0] == '*') && len(part) == 1 {
-			log.Fatal(fmt.Errorf("the routing path \"%s\" cannot contain nodes with only \"*\" or \":\"", strings.Join(parts, "/")))
-		}
 		if part[0] == '*' {
 			keys[i] = part[1:]
 			if next == nil {
@@ -77,10 +119,11 @@ func (t *trieTreeR) insert(parts []string, handler Handler) int {
 	if cur.handler != nil {
 		t.heightNodeCount[height]--
 		isAdd = false
-		log.Warnf("A route coverage occurred in \"/%s\"", strings.Join(parts, "/"))
 	}
 	cur.handler = handler
 	cur.keys = keys
+	cur.pattern = "/" + strings.Join(parts, "/")
+	cur.name = name
 	t.heightNodeCount[height]++
 	t.maxDenseNodeCount = max(t.maxDenseNodeCount, t.heightNodeCount[height])
 	if isAdd {
@@ -90,6 +133,88 @@ func (t *trieTreeR) insert(parts []string, handler Handler) int {
 	}
 }
 
+func (t *trieTreeR) clone() *trieTreeR {
+	heightNodeCount := make(map[int]int, len(t.heightNodeCount))
+	for height, count := range t.heightNodeCount {
+		heightNodeCount[height] = count
+	}
+	return &trieTreeR{t.root.clone(), heightNodeCount, t.maxDenseNodeCount}
+}
+
+// walk returns the nodes from root to the node registered by parts, or nil if it does not exist
+func (t *trieTreeR) walk(parts []string) []*nodeR {
+	nodes := make([]*nodeR, 1, len(parts)+1)
+	nodes[0] = t.root
+	for _, part := range parts {
+		next := nodes[len(nodes)-1].structChild(part)
+		if next == nil {
+			return nil
+		}
+		nodes = append(nodes, next)
+		if part[0] == '*' {
+			break
+		}
+	}
+	return nodes
+}
+
+// prune removes the empty nodes at the end of the walked nodes
+func (t *trieTreeR) prune(nodes []*nodeR, parts []string) {
+	for i := len(nodes) - 1; i > 0 && nodes[i].isEmpty(); i-- {
+		nodes[i-1].detachChild(parts[i-1])
+	}
+}
+
+func (t *trieTreeR) remove(parts []string) bool {
+	nodes := t.walk(parts)
+	if nodes == nil || nodes[len(nodes)-1].handler == nil {
+		return false
+	}
+	cur := nodes[len(nodes)-1]
+	cur.handler = nil
+	cur.keys = nil
+	cur.pattern = ""
+	cur.name = ""
+	t.heightNodeCount[len(nodes)-1]--
+	t.prune(nodes, parts)
+	return true
+}
+
+// removeSubtree removes all routes which start with parts and returns the count of them
+func (t *trieTreeR) removeSubtree(parts []string) int {
+	nodes := t.walk(parts)
+	if nodes == nil {
+		return 0
+	}
+	height := len(nodes) - 1
+	cnt := t.uncount(nodes[height], height)
+	if height == 0 {
+		t.root = newNodeR()
+		return cnt
+	}
+	nodes[height-1].detachChild(parts[height-1])
+	t.prune(nodes[:height], parts)
+	return cnt
+}
+
+// uncount drops the routes under n from heightNodeCount and returns the count of them
+func (t *trieTreeR) uncount(n *nodeR, height int) int {
+	if n == nil {
+		return 0
+	}
+	cnt := 0
+	if n.handler != nil {
+		t.heightNodeCount[height]--
+		cnt++
+	}
+	for _, child := range n.children {
+		cnt += t.uncount(child, height+1)
+	}
+	cnt += t.uncount(n.jumpChild, height+1)
+	cnt += t.uncount(n.stopChild, height+1)
+	return cnt
+}
+
 func (t *trieTreeR) search(parts []string) (*nodeR, map[string]string) {
 	queue := make([]*nodeR, 1, t.maxDenseNodeCount<<1)
 	queue[0] = t.root
@@ -0,0 +1,145 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version describes an API version served by a RouterGroup
+type Version struct {
+	Name        string    // the path segment of the version, such as "v1"
+	Default     bool      // whether the unversioned requests fall back to this version
+	Deprecation time.Time // the time when the version was deprecated, zero means not deprecated
+	Sunset      time.Time // the time when the version will be removed, zero means unknown
+}
+
+// versionSet stores all versions under the prefix of a group
+type versionSet struct {
+	prefix      string
+	header      string
+	defaultName string
+	versions    map[string]Version
+}
+
+func (vs *versionSet) clone() *versionSet {
+	versions := make(map[string]Version, len(vs.versions))
+	for name, v := range vs.versions {
+		versions[name] = v
+	}
+	return &versionSet{prefix: vs.prefix, header: vs.header, defaultName: vs.defaultName, versions: versions}
+}
+
+// acceptVersion picks the version out of vendor media types like "application/vnd.x.v2+json"
+func acceptVersion(accept string) string {
+	for _, mediaType := range strings.Split(accept, ",") {
+		mediaType = strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0])
+		if !strings.HasPrefix(mediaType, "application/vnd.") {
+			continue
+		}
+		mediaType = strings.SplitN(mediaType, "+", 2)[0]
+		if idx := strings.LastIndexByte(mediaType, '.'); idx >= len("application/vnd.") {
+			return mediaType[idx+1:]
+		}
+	}
+	return ""
+}
+
+// resolve rewrites the path of the unversioned request and marks the deprecated versions,
+// the path is only rewritten to a route that exists, and the unversioned routes are kept
+// unless the request asks for a version explicitly
+func (vs *versionSet) resolve(t *routingTable, c *Context) {
+	rest := c.Path[len(vs.prefix):]
+	segment := strings.SplitN(strings.TrimPrefix(rest, "/"), "/", 2)[0]
+	v, has := vs.versions[segment]
+	if !has {
+		name := ""
+		if vs.header != "" {
+			name = c.GetHeader(vs.header)
+		}
+		if name == "" {
+			name = acceptVersion(c.GetHeader("Accept"))
+		}
+		explicit := name != ""
+		if !explicit {
+			name = vs.defaultName
+		}
+		if v, has = vs.versions[name]; !has {
+			return
+		}
+		path := vs.prefix + "/" + v.Name + rest
+		if n, _ := t.getRoute(c.Method, path); n == nil {
+			return
+		}
+		if n, _ := t.getRoute(c.Method, c.Path); n != nil && !explicit {
+			return
+		}
+		c.Path = path
+	}
+	if !v.Deprecation.IsZero() {
+		c.SetHeader("Deprecation", "@"+strconv.FormatInt(v.Deprecation.Unix(), 10))
+	}
+	if !v.Sunset.IsZero() {
+		c.SetHeader("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
+// versioning resolves the version of the request by the deepest matched version set
+func (t *routingTable) versioning(c *Context) {
+	var matched *versionSet
+	for prefix, vs := range t.versions {
+		if (c.Path == prefix || strings.HasPrefix(c.Path, prefix+"/")) && (matched == nil || len(prefix) > len(matched.prefix)) {
+			matched = vs
+		}
+	}
+	if matched != nil {
+		matched.resolve(t, c)
+	}
+}
+
+// updateVersions applies fn to a copy of the version set at prefix
+func (r *router) updateVersions(prefix string, fn func(vs *versionSet)) {
+	r.update(func(t *routingTable) {
+		vs, has := t.versions[prefix]
+		if has {
+			vs = vs.clone()
+		} else {
+			vs = &versionSet{prefix: prefix, versions: make(map[string]Version)}
+		}
+		fn(vs)
+		versions := make(map[string]*versionSet, len(t.versions)+1)
+		for p, v := range t.versions {
+			versions[p] = v
+		}
+		versions[prefix] = vs
+		t.versions = versions
+	})
+}
+
+// Version is defined to create the group of an API version under this group.
+// The version is picked by the URL prefix, the vendor media type in "Accept" such as
+// "application/vnd.x.v2+json" or the header set by VersionHeader, and the unversioned
+// requests fall back to the default version unless a route of the group matches them.
+// Deprecated versions get the
+// "Deprecation" and "Sunset" headers automatically.
+func (group *RouterGroup) Version(version Version) *RouterGroup {
+	if version.Name == "" || strings.ContainsAny(version.Name, "/:*") {
+		panic("the version name must be a plain path segment")
+	}
+	group.server.router.updateVersions(group.prefix, func(vs *versionSet) {
+		if version.Default {
+			vs.defaultName = version.Name
+		}
+		vs.versions[version.Name] = version
+	})
+	return group.Group(version.Name)
+}
+
+// VersionHeader is defined to set the custom header which carries the version of the requests to this group
+func (group *RouterGroup) VersionHeader(header string) *RouterGroup {
+	group.server.router.updateVersions(group.prefix, func(vs *versionSet) {
+		vs.header = header
+	})
+	return group
+}
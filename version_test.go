@@ -0,0 +1,43 @@
+package web
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVersion(t *testing.T) {
+	s := New()
+	api := s.Group("api").VersionHeader("X-API-Version")
+	v1 := api.Version(Version{Name: "v1", Deprecation: time.Unix(1700000000, 0), Sunset: time.Unix(1800000000, 0)})
+	v2 := api.Version(Version{Name: "v2", Default: true})
+	v1.GET("/users", HandlerFunc(func(c *Context) { c.String(200, "v1") }))
+	v2.GET("/users", HandlerFunc(func(c *Context) { c.String(200, "v2") }))
+	api.GET("/health", HandlerFunc(func(c *Context) { c.String(200, "health") }))
+
+	cases := []struct {
+		path, header, value, expect string
+	}{
+		{"/api/v1/users", "", "", "v1"},
+		{"/api/users", "", "", "v2"},
+		{"/api/users", "X-API-Version", "v1", "v1"},
+		{"/api/users", "Accept", "application/vnd.x.v1+json", "v1"},
+		{"/api/health", "", "", "health"},
+		{"/api/health", "X-API-Version", "v1", "health"},
+		{"/api/v9/users", "", "", "404 NOT FOUND: /api/v9/users"},
+	}
+	for _, cs := range cases {
+		req := httptest.NewRequest("GET", cs.path, nil)
+		if cs.header != "" {
+			req.Header.Set(cs.header, cs.value)
+		}
+		w := httptest.NewRecorder()
+		(&Engine{s}).ServeHTTP(w, req)
+		if w.Body.String() != cs.expect {
+			t.Fatalf("%s %s=%s: expected %s, got %q", cs.path, cs.header, cs.value, cs.expect, w.Body.String())
+		}
+		if cs.expect == "v1" && (w.Header().Get("Deprecation") != "@1700000000" || w.Header().Get("Sunset") == "") {
+			t.Fatalf("expected deprecation headers, got %v", w.Header())
+		}
+	}
+}
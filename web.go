@@ -1,13 +1,19 @@
 package web
 
 import (
+	"context"
+	"crypto/cipher"
+	"errors"
 	"fmt"
 	"github.com/go-needle/web/log"
 	"html/template"
+	"log/slog"
 	"net"
 	"net/http"
+	"os"
 	"path"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -80,10 +86,18 @@ func (group *RouterGroup) Group(prefix string) *RouterGroup {
 		parent: group,
 		server: server,
 	}
-	server.groups.insert(groupPrefix, newGroup)
+	server.router.addGroup(newGroup)
 	return newGroup
 }
 
+// Remove is defined to remove the group with its sub groups and all routes under its prefix at runtime
+func (group *RouterGroup) Remove() {
+	if group.parent == nil {
+		panic("the root group cannot be removed")
+	}
+	group.server.router.removeGroup(group)
+}
+
 func (group *RouterGroup) addRoute(method string, comp string, handler Handler) {
 	pattern := group.prefix + comp
 	group.server.router.addRoute(method, pattern, handler)
@@ -91,7 +105,7 @@ func (group *RouterGroup) addRoute(method string, comp string, handler Handler)
 
 // Use is defined to add middleware to the group
 func (group *RouterGroup) Use(middlewares ...Handler) *RouterGroup {
-	group.middlewares = append(group.middlewares, middlewares...)
+	group.server.router.useGroup(group, middlewares...)
 	return group
 }
 
@@ -113,6 +127,28 @@ func (group *RouterGroup) REQUEST(method, pattern string, handler Handler) {
 	group.addRoute(method, pattern, handler)
 }
 
+// RemoveRoute is defined to remove the route of method and pattern at runtime, it reports whether the route existed
+func (group *RouterGroup) RemoveRoute(method, pattern string) bool {
+	if len(pattern) == 0 || pattern[0] != '/' {
+		pattern = "/" + pattern
+	}
+	return group.server.router.removeRoute(method, group.prefix+pattern)
+}
+
+type namedHandler struct {
+	name    string
+	handler Handler
+}
+
+func (h *namedHandler) Handle(c *Context) {
+	h.handler.Handle(c)
+}
+
+// Named gives the route handler a name which is read by c.RouteName(), such as s.GET("/users/:id", web.Named("user", h))
+func Named(name string, handler Handler) Handler {
+	return &namedHandler{name, handler}
+}
+
 // GET defines the method to add GET request
 func (group *RouterGroup) GET(pattern string, handler Handler) {
 	group.REQUEST("GET", pattern, handler)
@@ -172,18 +208,28 @@ func (group *RouterGroup) Static(relativePath string, root string) {
 	group.GET(urlPattern, handler)
 }
 
+// defaultLogger writes the records through the log of web
+var defaultLogger = slog.New(log.NewHandler())
+
 type Server struct {
 	*RouterGroup
-	router        *router
-	groups        *trieTreeG         // store all groups
+	router        *router            // store all routes and groups
 	htmlTemplates *template.Template // for html render
 	funcMap       template.FuncMap   // for html render
+	cookieDomain  string             // default domain of cookies
+	cookiePath    string             // default path of cookies
+	cookieKeys    [][]byte           // HMAC keys of signed cookies
+	cookieCiphers []cipher.AEAD      // AES-GCM ciphers of encrypted cookies
+	logger        *slog.Logger       // all framework messages go through it
+	mode          string             // ReleaseMode or DebugMode
+	health        *Health            // liveness and readiness checks
+	httpServer    atomic.Pointer[http.Server]
 }
 
 func newServer() *Server {
-	server := &Server{router: newRouter()}
+	server := &Server{funcMap: template.FuncMap{"csrfField": csrfField}, logger: defaultLogger, mode: ReleaseMode, health: newHealth()}
 	server.RouterGroup = &RouterGroup{server: server}
-	server.groups = newTrieTreeG(server.RouterGroup)
+	server.router = newRouter(server.RouterGroup)
 	return server
 }
 
@@ -201,12 +247,20 @@ func Default() *Server {
 
 // Use is defined to add middleware to the server
 func (server *Server) Use(middlewares ...Handler) *Server {
-	server.middlewares = append(server.middlewares, middlewares...)
+	server.RouterGroup.Use(middlewares...)
 	return server
 }
 
+// SetLogger sets the logger of all framework messages, default is the log of web
+func (server *Server) SetLogger(logger *slog.Logger) {
+	server.logger = logger
+}
+
+// SetFuncMap adds the functions to the funcMap for html render, the built-in "csrfField" can be overridden
 func (server *Server) SetFuncMap(funcMap template.FuncMap) {
-	server.funcMap = funcMap
+	for name, fn := range funcMap {
+		server.funcMap[name] = fn
+	}
 }
 
 func (server *Server) LoadHTMLGlob(pattern string) {
@@ -234,36 +288,78 @@ func getInternalIP() (string, error) {
 	return "", fmt.Errorf("no internal IP address found, check for multiple interfaces")
 }
 
-func welcome(routerNum int) {
+func (server *Server) welcome() {
 	time.Sleep(time.Millisecond * 100)
-	log.Info("🪡 Welcome to use go-needle-web")
-	log.Info("🪡 Available router total: " + strconv.Itoa(routerNum))
+	server.logger.Info("🪡 Welcome to use go-needle-web")
+	server.logger.Info("🪡 Available router total", "routes", server.router.load().total)
 	ip, err := getInternalIP()
 	if err == nil {
-		log.Info("🪡 IP address: " + ip)
+		server.logger.Info("🪡 IP address", "ip", ip)
 	}
 }
 
-// Run defines the method to start a http server
+// Run defines the method to start a http server, it returns after Shutdown
 func (server *Server) Run(port int) {
 	portStr := strconv.Itoa(port)
-	welcome(server.router.total)
-	log.Info("🪡 The http server is listening at port " + portStr)
-	log.Fatal(http.ListenAndServe(":"+portStr, &Engine{server}))
+	server.welcome()
+	server.logger.Info("🪡 The http server is listening", "port", port)
+	srv := &http.Server{Addr: ":" + portStr, Handler: &Engine{server}}
+	server.httpServer.Store(srv)
+	server.exitOnError(srv.ListenAndServe())
 }
 
-// RunTLS defines the method to start a https server
+// RunTLS defines the method to start a https server, it returns after Shutdown
 func (server *Server) RunTLS(port int, certFile, keyFile string) {
 	portStr := strconv.Itoa(port)
-	welcome(server.router.total)
-	log.Info("🪡 The https server is listening at port " + portStr)
-	log.Fatal(http.ListenAndServeTLS(":"+portStr, certFile, keyFile, &Engine{server}))
+	server.welcome()
+	server.logger.Info("🪡 The https server is listening", "port", port)
+	srv := &http.Server{Addr: ":" + portStr, Handler: &Engine{server}}
+	server.httpServer.Store(srv)
+	server.exitOnError(srv.ListenAndServeTLS(certFile, keyFile))
+}
+
+// exitOnError exits unless the server is closed by Shutdown
+func (server *Server) exitOnError(err error) {
+	if errors.Is(err, http.ErrServerClosed) {
+		server.logger.Info("🪡 The server is closed")
+		return
+	}
+	server.logger.Error(err.Error())
+	os.Exit(1)
+}
+
+// Shutdown gracefully shuts down the server started by Run or RunTLS, the readiness fails at once
+// and the listener is closed after the shutdown delay of Health
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.health.shuttingDown.Store(true)
+	server.health.mu.RLock()
+	delay := server.health.shutdownDelay
+	server.health.mu.RUnlock()
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if srv := server.httpServer.Load(); srv != nil {
+		return srv.Shutdown(ctx)
+	}
+	return nil
+}
+
+// ServeHTTP makes the server a http.Handler, so that it can be served by http.Server or httptest
+func (server *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	(&Engine{server}).ServeHTTP(w, req)
 }
 
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	middlewaresFind := engine.server.groups.search(req.URL.Path)
+	table := engine.server.router.load()
 	c := newContext(w, req)
-	c.handlers = middlewaresFind
 	c.server = engine.server
-	engine.server.router.handle(c)
+	c.writer.logger = engine.server.logger
+	table.versioning(c)
+	c.handlers = table.groups.search(c.Path)
+	table.handle(c)
+	c.Writer.WriteHeaderNow()
 }
@@ -0,0 +1,348 @@
+// Package webtest sends requests to a web.Server in memory and asserts the responses.
+package webtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-needle/web"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Client sends the requests to the handler and keeps the cookies across them like a browser
+type Client struct {
+	handler http.Handler
+	baseURL *url.URL
+	header  http.Header
+	jar     http.CookieJar
+}
+
+// New is the constructor of Client, the requests are sent to "http://example.com" by default
+func New(s *web.Server) *Client {
+	return NewWithHandler(s)
+}
+
+// NewWithHandler is the constructor of Client for any http.Handler
+func NewWithHandler(h http.Handler) *Client {
+	jar, _ := cookiejar.New(nil)
+	baseURL, _ := url.Parse("http://example.com")
+	return &Client{handler: h, baseURL: baseURL, header: make(http.Header), jar: jar}
+}
+
+// BaseURL sets the scheme and host of the requests, use "https://..." to receive the secure cookies
+func (c *Client) BaseURL(rawURL string) *Client {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		panic("webtest: invalid base url " + rawURL)
+	}
+	c.baseURL = u
+	return c
+}
+
+// SetHeader sets the header which is sent with all requests of the client
+func (c *Client) SetHeader(key, value string) *Client {
+	c.header.Set(key, value)
+	return c
+}
+
+// Jar returns the cookie jar of the client
+func (c *Client) Jar() http.CookieJar {
+	return c.jar
+}
+
+// ClearCookies drops all cookies of the client
+func (c *Client) ClearCookies() *Client {
+	c.jar, _ = cookiejar.New(nil)
+	return c
+}
+
+// Request starts a request of the method and path, the path may contain the query
+func (c *Client) Request(method, path string) *Request {
+	return &Request{client: c, method: method, path: path, header: c.header.Clone(), query: make(url.Values)}
+}
+
+// GET starts a GET request
+func (c *Client) GET(path string) *Request {
+	return c.Request(http.MethodGet, path)
+}
+
+// POST starts a POST request
+func (c *Client) POST(path string) *Request {
+	return c.Request(http.MethodPost, path)
+}
+
+// PUT starts a PUT request
+func (c *Client) PUT(path string) *Request {
+	return c.Request(http.MethodPut, path)
+}
+
+// PATCH starts a PATCH request
+func (c *Client) PATCH(path string) *Request {
+	return c.Request(http.MethodPatch, path)
+}
+
+// DELETE starts a DELETE request
+func (c *Client) DELETE(path string) *Request {
+	return c.Request(http.MethodDelete, path)
+}
+
+// HEAD starts a HEAD request
+func (c *Client) HEAD(path string) *Request {
+	return c.Request(http.MethodHead, path)
+}
+
+// OPTIONS starts an OPTIONS request
+func (c *Client) OPTIONS(path string) *Request {
+	return c.Request(http.MethodOptions, path)
+}
+
+// Request is built fluently and sent by Do
+type Request struct {
+	client  *Client
+	method  string
+	path    string
+	header  http.Header
+	query   url.Values
+	cookies []*http.Cookie
+	body    []byte
+}
+
+// Header sets the header of the request
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// Query adds the query parameter of the request
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// Cookie adds the cookie to the request besides the cookies of the jar
+func (r *Request) Cookie(name, value string) *Request {
+	r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: value})
+	return r
+}
+
+// Body sets the raw body of the request
+func (r *Request) Body(contentType string, body []byte) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+// JSON encodes v as the body of the request
+func (r *Request) JSON(v any) *Request {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic("webtest: " + err.Error())
+	}
+	return r.Body("application/json", body)
+}
+
+// Form encodes the values as the url-encoded form body of the request
+func (r *Request) Form(values url.Values) *Request {
+	return r.Body("application/x-www-form-urlencoded", []byte(values.Encode()))
+}
+
+// Do serves the request in memory and stores the cookies of the response into the jar
+func (r *Request) Do() *Response {
+	u, err := r.client.baseURL.Parse(r.path)
+	if err != nil {
+		panic("webtest: invalid path " + r.path)
+	}
+	if len(r.query) > 0 {
+		query := u.Query()
+		for key, values := range r.query {
+			query[key] = append(query[key], values...)
+		}
+		u.RawQuery = query.Encode()
+	}
+	req := httptest.NewRequest(r.method, u.String(), bytes.NewReader(r.body))
+	req.Header = r.header.Clone()
+	for _, cookie := range r.client.jar.Cookies(u) {
+		req.AddCookie(cookie)
+	}
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.client.handler.ServeHTTP(w, req)
+	res := w.Result()
+	res.Request = req // the recorder leaves it nil
+	r.client.jar.SetCookies(u, res.Cookies())
+	return &Response{Response: res, body: w.Body.Bytes()}
+}
+
+// Response is the recorded response of a request
+type Response struct {
+	*http.Response
+	body []byte
+}
+
+// Bytes returns the body
+func (r *Response) Bytes() []byte {
+	return r.body
+}
+
+// String returns the body as string
+func (r *Response) String() string {
+	return string(r.body)
+}
+
+// DecodeJSON decodes the body into v
+func (r *Response) DecodeJSON(v any) error {
+	return json.Unmarshal(r.body, v)
+}
+
+// Cookie returns the cookie of the name set by the response, or nil
+func (r *Response) Cookie(name string) *http.Cookie {
+	for _, cookie := range r.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// JSONPath returns the value at the path of the JSON body such as "items.0.name" or "items[0].name"
+func (r *Response) JSONPath(path string) (any, error) {
+	var v any
+	if err := json.Unmarshal(r.body, &v); err != nil {
+		return nil, err
+	}
+	return lookup(v, path)
+}
+
+func lookup(v any, path string) (any, error) {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	if path == "" {
+		return v, nil
+	}
+	for _, key := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			value, has := node[key]
+			if !has {
+				return nil, fmt.Errorf("key %q not found", key)
+			}
+			v = value
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("index %q out of range", key)
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("cannot index %T by %q", v, key)
+		}
+	}
+	return v, nil
+}
+
+// normalize converts v to the value decoded from its JSON, so that 1 equals float64(1)
+func normalize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic("webtest: " + err.Error())
+	}
+	var n any
+	_ = json.Unmarshal(data, &n)
+	return n
+}
+
+// Expect starts the assertions on the response, failures are reported by t.Errorf
+func (r *Response) Expect(t testing.TB) *Expectation {
+	return &Expectation{t: t, res: r}
+}
+
+// Expectation asserts the response fluently
+type Expectation struct {
+	t   testing.TB
+	res *Response
+}
+
+// Status asserts the status code
+func (e *Expectation) Status(code int) *Expectation {
+	e.t.Helper()
+	if e.res.StatusCode != code {
+		e.t.Errorf("%s %s: expected status %d, got %d: %s", e.res.Request.Method, e.res.Request.URL.Path, code, e.res.StatusCode, e.res.body)
+	}
+	return e
+}
+
+// Header asserts the value of the header
+func (e *Expectation) Header(key, value string) *Expectation {
+	e.t.Helper()
+	if got := e.res.Header.Get(key); got != value {
+		e.t.Errorf("expected header %s %q, got %q", key, value, got)
+	}
+	return e
+}
+
+// Body asserts the whole body
+func (e *Expectation) Body(body string) *Expectation {
+	e.t.Helper()
+	if got := e.res.String(); got != body {
+		e.t.Errorf("expected body %q, got %q", body, got)
+	}
+	return e
+}
+
+// BodyContains asserts the body contains the substring
+func (e *Expectation) BodyContains(sub string) *Expectation {
+	e.t.Helper()
+	if !strings.Contains(e.res.String(), sub) {
+		e.t.Errorf("expected body to contain %q, got %q", sub, e.res.String())
+	}
+	return e
+}
+
+// JSON asserts the JSON body equals v in JSON
+func (e *Expectation) JSON(v any) *Expectation {
+	e.t.Helper()
+	return e.JSONPath("", v)
+}
+
+// JSONPath asserts the value at the path of the JSON body equals v in JSON
+func (e *Expectation) JSONPath(path string, v any) *Expectation {
+	e.t.Helper()
+	got, err := e.res.JSONPath(path)
+	if err != nil {
+		e.t.Errorf("json path %q: %v in %s", path, err, e.res.body)
+		return e
+	}
+	if expected := normalize(v); !reflect.DeepEqual(got, expected) {
+		e.t.Errorf("json path %q: expected %#v, got %#v", path, expected, got)
+	}
+	return e
+}
+
+// Cookie asserts the response sets the cookie of the name to the value
+func (e *Expectation) Cookie(name, value string) *Expectation {
+	e.t.Helper()
+	cookie := e.res.Cookie(name)
+	if cookie == nil {
+		e.t.Errorf("expected cookie %s to be set", name)
+	} else if cookie.Value != value {
+		e.t.Errorf("expected cookie %s %q, got %q", name, value, cookie.Value)
+	}
+	return e
+}
+
+// HasCookie asserts the response sets the cookie of the name
+func (e *Expectation) HasCookie(name string) *Expectation {
+	e.t.Helper()
+	if e.res.Cookie(name) == nil {
+		e.t.Errorf("expected cookie %s to be set", name)
+	}
+	return e
+}
@@ -0,0 +1,87 @@
+package webtest
+
+import (
+	"fmt"
+	"github.com/go-needle/web"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	s := web.New()
+	s.Use(web.Sessions(web.SessionConfig{}))
+	s.POST("/login", web.HandlerFunc(func(c *web.Context) {
+		var body struct{ Name string }
+		if _, err := c.BindJson(&body); err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.Session().Set("user", body.Name)
+		c.SetCookie(&web.Cookie{Name: "theme", Value: "dark"})
+		c.SetHeader("X-Trace", c.GetHeader("X-Trace"))
+		c.JSON(http.StatusOK, web.H{"user": web.H{"name": body.Name, "roles": []string{"admin", "dev"}}, "age": 30})
+	}))
+	s.GET("/me", web.HandlerFunc(func(c *web.Context) {
+		user, _ := c.Session().Get("user").(string)
+		if user == "" {
+			c.Fail(http.StatusUnauthorized, "401 UNAUTHORIZED")
+			return
+		}
+		theme, _ := c.Cookie("theme")
+		c.String(http.StatusOK, "%s %s %s", user, c.Query("v"), theme)
+	}))
+
+	client := New(s)
+	client.GET("/me").Do().Expect(t).Status(http.StatusUnauthorized)
+	client.POST("/login").Header("X-Trace", "t1").JSON(web.H{"name": "bob"}).Do().Expect(t).
+		Status(http.StatusOK).
+		Header("X-Trace", "t1").
+		HasCookie("session").
+		Cookie("theme", "dark").
+		JSONPath("user.name", "bob").
+		JSONPath("user.roles[1]", "dev").
+		JSONPath("age", 30)
+	client.GET("/me").Query("v", "1").Do().Expect(t).Status(http.StatusOK).Body("bob 1 dark")
+
+	client.ClearCookies()
+	client.GET("/me").Do().Expect(t).Status(http.StatusUnauthorized)
+
+	if _, err := client.POST("/login").JSON(web.H{"name": "amy"}).Do().JSONPath("user.missing"); err == nil {
+		t.Fatal("expected the missing path to fail")
+	}
+}
+
+// fakeT records the failures instead of failing the test
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestExpectationFailures(t *testing.T) {
+	s := web.New()
+	s.GET("/x", web.HandlerFunc(func(c *web.Context) { c.JSON(http.StatusOK, web.H{"a": 1}) }))
+	ft := &fakeT{}
+	New(s).GET("/nope").Do().Expect(ft).Status(http.StatusOK)
+	New(s).GET("/x").Do().Expect(ft).Header("X-Missing", "1").JSONPath("a", 2).Cookie("c", "v")
+	expected := []string{
+		"GET /nope: expected status 200, got 404",
+		`expected header X-Missing "1", got ""`,
+		`json path "a": expected 2, got 1`,
+		"expected cookie c to be set",
+	}
+	if len(ft.errors) != len(expected) {
+		t.Fatalf("unexpected failures %q", ft.errors)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(ft.errors[i], prefix) {
+			t.Errorf("expected failure %q, got %q", prefix, ft.errors[i])
+		}
+	}
+}